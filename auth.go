@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v74/github"
+	"golang.org/x/term"
+)
+
+// scope a classic personal access token needs to manage gists
+const requiredTokenScope = "gist"
+
+// layout used by the GitHub-Authentication-Token-Expiration header
+const tokenExpirationLayout = "2006-01-02 15:04:05 MST"
+
+var (
+	err_token_invalid            = errors.New("Token is invalid, expired or revoked, generate a new one and log in again")
+	err_token_expired            = errors.New("Token has expired, generate a new one and log in again")
+	err_token_missing_scope      = errors.New(`Token is missing the "gist" scope, tick it in the token settings and log in again`)
+	err_token_missing_permission = errors.New(`Fine-grained token needs the "Gists" account permission with read and write access`)
+)
+
+type tokenInfo struct {
+	login       string
+	fineGrained bool
+	scopes      []string
+	// zero value means the token never expires
+	expiresAt time.Time
+}
+
+// readSecret reads a line of stdin without echoing it when the input is a terminal
+func readSecret() (string, error) {
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		secret, err := term.ReadPassword(fd)
+		fmt.Println()
+		return string(secret), err
+	}
+	line, err := stdin.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return line, nil
+}
+
+func (t *tokenInfo) expiresSoon() bool {
+	return !t.expiresAt.IsZero() && time.Until(t.expiresAt) < 7*24*time.Hour
+}
+
+// validateToken makes sure the token behind the client can actually manage gists,
+// so that a bad token fails at login instead of on the first edit
+func validateToken(ctx context.Context, client *github.Client) (*tokenInfo, error) {
+	user, resp, err := client.Users.Get(ctx, "")
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return nil, err_token_invalid
+		}
+		return nil, err
+	}
+
+	info := &tokenInfo{login: user.GetLogin()}
+
+	if exp := resp.Header.Get("GitHub-Authentication-Token-Expiration"); exp != "" {
+		if t, err := time.Parse(tokenExpirationLayout, exp); err == nil {
+			info.expiresAt = t.In(time.Local)
+		}
+	}
+	if !info.expiresAt.IsZero() && time.Now().After(info.expiresAt) {
+		return info, err_token_expired
+	}
+
+	// classic tokens always report their scopes, fine-grained tokens never do
+	if _, ok := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; ok {
+		for _, scope := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				info.scopes = append(info.scopes, scope)
+			}
+		}
+		if !slices.Contains(info.scopes, requiredTokenScope) {
+			return info, err_token_missing_scope
+		}
+		return info, nil
+	}
+
+	info.fineGrained = true
+
+	// fine-grained permissions are not exposed anywhere and probing them would take a write,
+	// listing the gists is read-only and at least fails for tokens that can't reach them.
+	// missing write access only shows up on the first edit
+	_, resp, err = client.Gists.List(ctx, "", &github.GistListOptions{ListOptions: github.ListOptions{PerPage: 1}})
+	if resp != nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnauthorized) {
+		return info, err_token_missing_permission
+	}
+	return info, err
+}
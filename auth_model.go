@@ -8,6 +8,7 @@ import (
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/google/go-github/v74/github"
 )
 
@@ -25,6 +26,8 @@ type authModel struct {
 	form           *huh.Form
 	width          int
 	height         int
+	// reason why the last authentication attempt failed
+	err error
 
 	authCtx context.Context
 }
@@ -35,18 +38,49 @@ type authSuccessMsg struct {
 
 type needSecretsMsg struct{}
 
+type authFailedMsg struct {
+	err error
+}
+
 func (m *authModel) authenticate() tea.Cmd {
 	return func() tea.Msg {
 		ctx := context.Background()
-		client := github.NewClient(nil).WithAuthToken(cfg.AccessToken)
-		user, _, err := client.Users.Get(ctx, "")
-		if user == nil {
-			return showInfo(err.Error(), info_error)
+		client := newClient(cfg.AccessToken)
+		if _, err := validateToken(ctx, client); err != nil {
+			return authFailedMsg{err}
 		}
 		return authSuccessMsg{client}
 	}
 }
 
+func (m *authModel) promptSecrets() tea.Cmd {
+	m.state = auth_prompt_secrets
+	// dont prefill the field with the token that just got rejected
+	token := ""
+	m.form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Github Personal Token").
+				Value(&token).
+				Key("access_token").
+				Validate(func(s string) error {
+					if s == "" {
+						return fmt.Errorf("client id required")
+					}
+					return nil
+				}),
+		),
+	)
+	if m.width > 0 && m.height > 0 {
+		var f tea.Model
+		f, _ = m.form.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+		if form, ok := f.(*huh.Form); ok {
+			m.form = form
+		}
+	}
+	return m.form.Init()
+}
+
 func (m authModel) Init() tea.Cmd {
 	if cfg.AccessToken == "" {
 		return func() tea.Msg { return needSecretsMsg{} }
@@ -58,6 +92,10 @@ func (m authModel) View() string {
 	switch m.state {
 	case auth_prompt_secrets:
 		if m.form != nil {
+			if m.err != nil {
+				errView := lipgloss.NewStyle().Foreground(lipgloss.Color("208")).Render("\ue654  " + m.err.Error())
+				return errView + "\n\n" + m.form.View()
+			}
 			return m.form.View()
 		}
 		return "Input required"
@@ -97,29 +135,12 @@ func (m authModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return msg
 		}
 	case needSecretsMsg:
-		m.state = auth_prompt_secrets
-		m.form = huh.NewForm(
-			huh.NewGroup(
-				huh.NewInput().
-					Title("Github Personal Token").
-					Value(&cfg.AccessToken).
-					Key("access_token").
-					Validate(func(s string) error {
-						if s == "" {
-							return fmt.Errorf("client id required")
-						}
-						return nil
-					}),
-			),
-		)
-		if m.width > 0 && m.height > 0 {
-			var f tea.Model
-			f, _ = m.form.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
-			if form, ok := f.(*huh.Form); ok {
-				m.form = form
-			}
-		}
-		return m, m.form.Init()
+		return m, m.promptSecrets()
+	case authFailedMsg:
+		log.Errorln(msg.err)
+		m.err = msg.err
+		// let the user enter another token instead of leaving them on the loading screen
+		return m, m.promptSecrets()
 	case infoMsg:
 		if msg.variant == info_error {
 			log.Errorln(msg.msg)
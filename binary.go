@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize"
+)
+
+// amount of bytes sniffed to decide whether a file is binary, same as git does
+const sniffLen = 8000
+
+// id of the image placed through the kitty graphics protocol, reusing it replaces the previous one
+const kittyImageId = 7331
+
+func isBinary(content []byte) bool {
+	sample := content[:min(len(content), sniffLen)]
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	valid := utf8.Valid(sample)
+	// dont count a multibyte rune cut in half by the sample against the file
+	if !valid && len(sample) < len(content) {
+		for cut := 1; cut < utf8.UTFMax && !valid; cut++ {
+			valid = utf8.Valid(sample[:len(sample)-cut])
+		}
+	}
+	return !valid
+}
+
+func supportsKittyGraphics() bool {
+	return os.Getenv("KITTY_WINDOW_ID") != "" || strings.Contains(os.Getenv("TERM"), "kitty")
+}
+
+// decodeImage decodes the binary file once it is loaded, so frames dont have to
+func decodeImage(content string) image.Image {
+	img, _, err := image.Decode(strings.NewReader(content))
+	if err != nil {
+		return nil
+	}
+	return img
+}
+
+// renderBinaryPreview shows a binary file in place of the editor, images get drawn and
+// anything else gets a hex dump of as much of the file as fits. with kitty the image was
+// transmitted already, so the frame only says where it goes
+func renderBinaryPreview(title, content string, img image.Image, kitty bool, width, height int) string {
+	data := []byte(content)
+	header := fmt.Sprintf(
+		"%s\n%s · %s\n\n",
+		title, humanize.Bytes(uint64(len(data))), http.DetectContentType(data),
+	)
+	height -= strings.Count(header, "\n")
+	if width <= 0 || height <= 0 {
+		return header
+	}
+
+	if img != nil {
+		if kitty {
+			return header + placeKitty(width, height)
+		}
+		return header + renderHalfBlocks(img, width, height)
+	}
+
+	// every line of the dump shows 16 bytes
+	limit := min(len(data), height*16)
+	return header + strings.TrimRight(hex.Dump(data[:limit]), "\n")
+}
+
+// deleteKittyImage removes the image and its placement once the preview is left
+func deleteKittyImage() string {
+	return fmt.Sprintf("\x1b_Ga=d,d=I,i=%d,q=2\x1b\\", kittyImageId)
+}
+
+// transmitKitty sends the image to the terminal without showing it, it stays there under its id
+// until it is deleted. it is empty when the image cannot be encoded
+func transmitKitty(img image.Image) string {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return ""
+	}
+	payload := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	// the payload has to be sent in chunks of at most 4096 bytes
+	var out strings.Builder
+	for i := 0; i < len(payload); i += 4096 {
+		chunk := payload[i:min(i+4096, len(payload))]
+		more := 0
+		if i+4096 < len(payload) {
+			more = 1
+		}
+		if i == 0 {
+			fmt.Fprintf(&out, "\x1b_Ga=t,f=100,i=%d,q=2,m=%d;%s\x1b\\", kittyImageId, more, chunk)
+		} else {
+			fmt.Fprintf(&out, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return out.String()
+}
+
+// placeKitty shows the transmitted image at the cursor, placing it again with the same id moves it
+func placeKitty(width, height int) string {
+	place := fmt.Sprintf("\x1b_Ga=p,i=%d,p=1,c=%d,r=%d,C=1,q=2\x1b\\", kittyImageId, width, height)
+	// reserve the rows the image is drawn over
+	return place + strings.Repeat("\n", height-1)
+}
+
+// renderHalfBlocks draws the image with upper half blocks, every cell shows two pixels stacked on top of each other
+func renderHalfBlocks(img image.Image, width, height int) string {
+	bounds := img.Bounds()
+	scale := max(float64(bounds.Dx())/float64(width), float64(bounds.Dy())/float64(height*2), 1)
+	cols := int(float64(bounds.Dx()) / scale)
+	rows := int(float64(bounds.Dy()) / scale / 2)
+
+	pixel := func(x, y int) (uint32, uint32, uint32) {
+		r, g, b, _ := img.At(bounds.Min.X+int(float64(x)*scale), bounds.Min.Y+int(float64(y)*scale)).RGBA()
+		return r >> 8, g >> 8, b >> 8
+	}
+
+	var out strings.Builder
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			tr, tg, tb := pixel(col, row*2)
+			br, bg, bb := pixel(col, row*2+1)
+			fmt.Fprintf(&out, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", tr, tg, tb, br, bg, bb)
+		}
+		out.WriteString("\x1b[0m")
+		if row < rows-1 {
+			out.WriteString("\n")
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ostafen/clover/v2/query"
+)
+
+const defaultAutosaveDelay = 3 * time.Second
+
+func autosaveDelay() time.Duration {
+	switch {
+	case cfg.AutosaveDelay < 0:
+		return 0
+	case cfg.AutosaveDelay == 0:
+		return defaultAutosaveDelay
+	}
+	return time.Duration(cfg.AutosaveDelay) * time.Second
+}
+
+// autosaveMsg fires once the editor was idle for the autosave delay, unless another edit came in since
+type autosaveMsg struct {
+	gen    int
+	fileId string
+}
+
+// scheduleAutosave restarts the idle timer of the editor
+func (m *mainModel) scheduleAutosave() tea.Cmd {
+	delay := autosaveDelay()
+	if delay == 0 || m.editorFile == "" {
+		return nil
+	}
+	m.autosaveGen++
+	msg := autosaveMsg{gen: m.autosaveGen, fileId: m.editorFile}
+	return tea.Tick(delay, func(time.Time) tea.Msg { return msg })
+}
+
+func (m *mainModel) autosave(msg autosaveMsg) []tea.Cmd {
+	if msg.gen != m.autosaveGen || msg.fileId != m.editorFile {
+		return nil
+	}
+	return m.stashEditor()
+}
+
+func loadBuffer(fileId string) (string, error) {
+	doc, err := storage.db.FindFirst(query.NewQuery(string(collectionGistContent)).Where(query.Field("id").Eq(fileId)))
+	if err != nil {
+		return "", err
+	}
+	if doc == nil {
+		return "", fmt.Errorf("could not find the unsaved changes of file %q", fileId)
+	}
+	buffer, _ := doc.Get("buffer").(string)
+	return buffer, nil
+}
+
+// storeBuffer keeps the unsaved buffer of a file next to its content, an empty buffer that is not dirty clears it
+func storeBuffer(fileId, buffer string, dirty bool) error {
+	q := query.NewQuery(string(collectionGistContent)).Where(query.Field("id").Eq(fileId))
+	return storage.db.Update(q, map[string]interface{}{
+		"buffer": buffer,
+		"dirty":  dirty,
+	})
+}
+
+// findFile looks a file up by id in every gist
+func (m *mainModel) findFile(id string) (*gist, file, bool) {
+	for g, items := range m.gists {
+		if idx := fileIndex(items, id); idx >= 0 {
+			f, _ := items[idx].(file)
+			return g, f, true
+		}
+	}
+	return nil, file{}, false
+}
+
+// stashEditor persists the editor buffer as a local draft when it differs from the saved content,
+// and clears the draft again once it doesn't
+func (m *mainModel) stashEditor() []tea.Cmd {
+	if m.editorFile == "" || m.editorBinary {
+		return nil
+	}
+	g, f, ok := m.findFile(m.editorFile)
+	if !ok {
+		return nil
+	}
+
+	buffer := m.editor.GetCurrentContent()
+	dirty := buffer != m.editorSaved
+	if !dirty && !f.dirty {
+		return nil
+	}
+	if dirty && f.dirty && buffer == m.editorStashed {
+		return nil
+	}
+
+	if !dirty {
+		buffer = ""
+	}
+	if err := storeBuffer(f.id, buffer, dirty); err != nil {
+		log.Errorf("could not keep the unsaved changes of %q\n%v", f.title, err)
+		return []tea.Cmd{showInfo("could not keep unsaved changes", info_error)}
+	}
+	m.editorStashed = buffer
+
+	f.dirty = dirty
+	return m.replaceFile(g, f)
+}
+
+// dirtyFiles counts the files with unsaved changes
+func (m *mainModel) dirtyFiles() int {
+	count := 0
+	for _, items := range m.gists {
+		for _, item := range items {
+			if f, ok := item.(file); ok && f.dirty {
+				count++
+			}
+		}
+	}
+	return count
+}
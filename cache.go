@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/ostafen/clover/v2/document"
+	"github.com/ostafen/clover/v2/query"
+)
+
+// cacheTransport sends conditional GET requests using the ETag and Last-Modified values of the
+// previous response, a 304 is served from the collection and does not count against the rate limit
+type cacheTransport struct {
+	base http.RoundTripper
+}
+
+// cache key is scoped to the token so switching accounts never serves another user's gists
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	h.Write([]byte(req.Header.Get("Authorization")))
+	h.Write([]byte(req.URL.String()))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	cached, err := storage.db.FindFirst(
+		query.NewQuery(string(collectionHttpCache)).Where(query.Field("key").Eq(key)),
+	)
+	if err != nil {
+		log.Errorf("could not read http cache for %s: %v", req.URL, err)
+		cached = nil
+	}
+
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if etag, _ := cached.Get("etag").(string); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified, _ := cached.Get("lastModified").(string); lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		body, _ := cached.Get("body").(string)
+		// a 304 leaves out most headers, the Link header of the cached page is needed to find the next one
+		if header, _ := cached.Get("header").(string); header != "" {
+			var cachedHeader http.Header
+			if err := json.Unmarshal([]byte(header), &cachedHeader); err == nil {
+				for name, values := range cachedHeader {
+					if _, ok := resp.Header[name]; !ok {
+						resp.Header[name] = values
+					}
+				}
+			}
+		}
+		resp.Body.Close()
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.Body = io.NopCloser(bytes.NewBufferString(body))
+		resp.ContentLength = int64(len(body))
+		resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		resp.Header.Set("X-From-Cache", "1")
+		return resp, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return resp, nil
+	}
+	if err := storeCached(key, map[string]any{
+		"key":          key,
+		"url":          req.URL.String(),
+		"etag":         etag,
+		"lastModified": lastModified,
+		"header":       string(header),
+		"body":         string(body),
+	}); err != nil {
+		log.Errorf("could not write http cache for %s: %v", req.URL, err)
+	}
+
+	return resp, nil
+}
+
+// prefetch workers share the transport, two of them must not insert the same key twice
+var cacheMu sync.Mutex
+
+// storeCached replaces the cached response of the key, or adds it when there is none yet
+func storeCached(key string, fields map[string]any) error {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	// looked up again under the lock, another request might have stored it in the meantime
+	doc, err := storage.db.FindFirst(
+		query.NewQuery(string(collectionHttpCache)).Where(query.Field("key").Eq(key)),
+	)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		doc = document.NewDocument()
+	}
+	doc.SetAll(fields)
+	return storage.db.Save(string(collectionHttpCache), doc)
+}
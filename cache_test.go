@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ostafen/clover/v2/query"
+)
+
+// fakeServer answers with the etag of the body and a 304 when the request already has it
+type fakeServer struct {
+	mu   sync.Mutex
+	body string
+}
+
+func (s *fakeServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	etag := `"` + s.body + `"`
+	header := http.Header{}
+	header.Set("ETag", etag)
+	if req.Header.Get("If-None-Match") == etag {
+		return &http.Response{StatusCode: http.StatusNotModified, Header: header, Body: http.NoBody, Request: req}, nil
+	}
+	header.Set("Link", `<https://api.github.com/gists?page=2>; rel="next"`)
+	return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(s.body)), Request: req}, nil
+}
+
+func cachedGet(t *testing.T, transport http.RoundTripper, url string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Authorization", "Bearer test")
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	return resp, string(body)
+}
+
+func TestCacheTransport(t *testing.T) {
+	server := &fakeServer{body: "first"}
+	transport := &cacheTransport{base: server}
+	url := "https://api.github.com/gists?per_page=100"
+
+	tests := []struct {
+		name      string
+		body      string
+		fromCache bool
+	}{
+		{name: "first request is stored", body: "first"},
+		{name: "unchanged content is served from the cache", body: "first", fromCache: true},
+		{name: "changed content replaces the cache", body: "second"},
+		{name: "replaced content is served from the cache", body: "second", fromCache: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.body = tt.body
+			resp, body := cachedGet(t, transport, url)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want 200", resp.StatusCode)
+			}
+			if body != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+			if got := resp.Header.Get("X-From-Cache") == "1"; got != tt.fromCache {
+				t.Errorf("served from cache = %v, want %v", got, tt.fromCache)
+			}
+			// paging follows the Link header, a 304 must not lose it
+			if !strings.Contains(resp.Header.Get("Link"), `rel="next"`) {
+				t.Errorf("Link header = %q, want the next page", resp.Header.Get("Link"))
+			}
+		})
+	}
+}
+
+func TestCacheTransportConcurrentWrites(t *testing.T) {
+	transport := &cacheTransport{base: &fakeServer{body: "shared"}}
+	url := "https://api.github.com/gists/concurrent"
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, url, nil)
+			resp, err := transport.RoundTrip(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	docs, err := storage.db.FindAll(query.NewQuery(string(collectionHttpCache)).Where(query.Field("url").Eq(url)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 {
+		t.Errorf("cached %d documents for the url, want 1", len(docs))
+	}
+}
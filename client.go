@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v74/github"
+)
+
+const (
+	maxRetries = 4
+	// base delay of the exponential backoff, doubled on every retry
+	retryBaseDelay = 1 * time.Second
+	// never sleep longer than this for a single retry, fail instead
+	maxRetryWait = 1 * time.Minute
+	// how long a single attempt may wait for the response headers, retries get their own.
+	// reading the body is not covered, large files take as long as they need
+	attemptTimeout = 15 * time.Second
+)
+
+var err_body_not_replayable = errors.New("cannot retry request, body is not replayable")
+
+// rateLimitState keeps the most recent rate limit reported by the api so the tui can display it
+type rateLimitState struct {
+	mu        sync.Mutex
+	known     bool
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+var rateLimit = new(rateLimitState)
+
+func (r *rateLimitState) update(h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	reset, _ := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.known = true
+	r.limit = limit
+	r.remaining = remaining
+	r.reset = time.Unix(reset, 0)
+}
+
+func (r *rateLimitState) get() (remaining, limit int, reset time.Time, known bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.remaining, r.limit, r.reset, r.known
+}
+
+// retryTransport retries transient server errors and rate limited requests with exponential backoff
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+func newClient(token string) *github.Client {
+	httpClient := &http.Client{
+		Transport: &cacheTransport{
+			base: &retryTransport{base: http.DefaultTransport},
+		},
+	}
+	return github.NewClient(httpClient).WithAuthToken(token)
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	// the primary rate limit is already used up, wait for the reset when it is close enough
+	if remaining, _, reset, known := rateLimit.get(); known && remaining == 0 {
+		if wait := time.Until(reset); wait > 0 && wait <= maxRetryWait {
+			if err := sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		r := req.Clone(attemptCtx)
+		if attempt > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				cancel()
+				return nil, err_body_not_replayable
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			r.Body = body
+		}
+
+		timeout := time.AfterFunc(attemptTimeout, cancel)
+		resp, err := t.base.RoundTrip(r)
+		timeout.Stop()
+		if err != nil {
+			cancel()
+			if ctx.Err() != nil || attempt >= maxRetries || !isRetryable(req.Method) {
+				return nil, err
+			}
+			log.Warnf("%s %s failed, retrying: %v", req.Method, req.URL.Path, err)
+			if err := sleep(ctx, backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// keep the attempt alive until the caller is done with the body
+		resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+
+		rateLimit.update(resp.Header)
+
+		wait, retry := shouldRetry(req, resp, attempt)
+		if !retry || attempt >= maxRetries || wait > maxRetryWait {
+			return resp, nil
+		}
+
+		log.Warnf("%s %s returned %d, retrying in %s", req.Method, req.URL.Path, resp.StatusCode, wait)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry decides whether the response is worth another attempt and how long to wait for it
+func shouldRetry(req *http.Request, resp *http.Response, attempt int) (time.Duration, bool) {
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		// secondary rate limits tell us exactly how long to back off
+		if s := resp.Header.Get("Retry-After"); s != "" {
+			if secs, err := strconv.Atoi(s); err == nil {
+				return time.Duration(secs) * time.Second, true
+			}
+			return backoff(attempt), true
+		}
+		// primary rate limit, wait until the window resets
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+			if err != nil {
+				return backoff(attempt), true
+			}
+			return time.Until(time.Unix(reset, 0)) + time.Second, true
+		}
+		return 0, false
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		// the request may have gone through, so dont risk creating a gist twice
+		return backoff(attempt), isRetryable(req.Method)
+	}
+	return 0, false
+}
+
+// isRetryable reports whether sending the request again is harmless when the first one might
+// have gone through. a retried delete of a gist that is already gone would report a 404
+func isRetryable(method string) bool {
+	return method != http.MethodPost && method != http.MethodDelete
+}
+
+func backoff(attempt int) time.Duration {
+	d := retryBaseDelay << attempt
+	// add up to 50% jitter so concurrent requests dont retry in lockstep
+	return d + rand.N(d/2)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
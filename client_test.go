@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestShouldRetry(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(30*time.Second).Unix(), 10)
+	tests := []struct {
+		name    string
+		method  string
+		status  int
+		headers map[string]string
+		retry   bool
+		// the wait has to land in [min, max]
+		min, max time.Duration
+	}{
+		{name: "ok", method: http.MethodGet, status: http.StatusOK},
+		{name: "not found", method: http.MethodGet, status: http.StatusNotFound},
+		{name: "forbidden without rate limit", method: http.MethodGet, status: http.StatusForbidden},
+		{
+			name: "retry after", method: http.MethodGet, status: http.StatusForbidden,
+			headers: map[string]string{"Retry-After": "7"},
+			retry:   true, min: 7 * time.Second, max: 7 * time.Second,
+		},
+		{
+			name: "too many requests with invalid retry after", method: http.MethodPost, status: http.StatusTooManyRequests,
+			headers: map[string]string{"Retry-After": "soon"},
+			retry:   true, min: retryBaseDelay, max: retryBaseDelay * 3 / 2,
+		},
+		{
+			name: "primary rate limit", method: http.MethodGet, status: http.StatusForbidden,
+			headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset},
+			retry:   true, min: 29 * time.Second, max: 32 * time.Second,
+		},
+		{name: "server error get", method: http.MethodGet, status: http.StatusBadGateway, retry: true, min: retryBaseDelay, max: retryBaseDelay * 3 / 2},
+		{name: "server error patch", method: http.MethodPatch, status: http.StatusServiceUnavailable, retry: true, min: retryBaseDelay, max: retryBaseDelay * 3 / 2},
+		{name: "server error post", method: http.MethodPost, status: http.StatusInternalServerError},
+		{name: "server error delete", method: http.MethodDelete, status: http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, "https://api.github.com/gists", nil)
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for k, v := range tt.headers {
+				resp.Header.Set(k, v)
+			}
+			wait, retry := shouldRetry(req, resp, 0)
+			if retry != tt.retry {
+				t.Fatalf("retry = %v, want %v", retry, tt.retry)
+			}
+			if retry && (wait < tt.min || wait > tt.max) {
+				t.Errorf("wait = %s, want between %s and %s", wait, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		base := retryBaseDelay << attempt
+		for range 20 {
+			if d := backoff(attempt); d < base || d >= base*3/2 {
+				t.Fatalf("backoff(%d) = %s, want in [%s, %s)", attempt, d, base, base*3/2)
+			}
+		}
+	}
+}
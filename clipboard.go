@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.design/x/clipboard"
+)
+
+var (
+	err_no_clipboard = errors.New("no clipboard available, install wl-clipboard or xclip")
+	err_no_paste     = errors.New("the clipboard can't be read here, install wl-clipboard or xclip")
+)
+
+// the system clipboard needs X or Wayland, servers and ssh sessions usually have neither
+var nativeClipboard bool
+
+// clipboardTool copies and pastes through external commands reading stdin and writing stdout
+type clipboardTool struct {
+	env   string
+	copy  []string
+	paste []string
+}
+
+// clipboardTools are tried in order, wayland first since xclip can end up on xwayland
+var clipboardTools = []clipboardTool{
+	{env: "WAYLAND_DISPLAY", copy: []string{"wl-copy"}, paste: []string{"wl-paste", "--no-newline"}},
+	{env: "DISPLAY", copy: []string{"xclip", "-selection", "clipboard"}, paste: []string{"xclip", "-selection", "clipboard", "-o"}},
+	{env: "DISPLAY", copy: []string{"xsel", "--clipboard", "--input"}, paste: []string{"xsel", "--clipboard", "--output"}},
+}
+
+func initClipboard() {
+	nativeClipboard = clipboard.Init() == nil
+}
+
+// externalClipboard finds the first tool that is installed and has a display to talk to
+func externalClipboard() (clipboardTool, bool) {
+	for _, tool := range clipboardTools {
+		if os.Getenv(tool.env) == "" {
+			continue
+		}
+		if _, err := exec.LookPath(tool.copy[0]); err == nil {
+			return tool, true
+		}
+	}
+	return clipboardTool{}, false
+}
+
+// terminals known to take OSC 52 writes, elsewhere the sequence may be dropped and the copy would silently do nothing
+var osc52Terminals = []string{"kitty", "alacritty", "wezterm", "foot", "ghostty", "iterm", "contour", "rio", "tmux"}
+
+// osc52Terminal reports whether the terminal is known to support OSC 52, or was said to in the config
+func osc52Terminal() bool {
+	if cfg != nil && cfg.OSC52 {
+		return true
+	}
+	// windows terminal
+	if os.Getenv("WT_SESSION") != "" {
+		return true
+	}
+	name := strings.ToLower(os.Getenv("TERM_PROGRAM") + " " + os.Getenv("TERM"))
+	for _, terminal := range osc52Terminals {
+		if strings.Contains(name, terminal) {
+			return true
+		}
+	}
+	return false
+}
+
+// osc52Available reports whether copies can go through the terminal, which works over ssh and tmux
+func osc52Available() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0 && osc52Terminal()
+}
+
+func canCopy() bool {
+	_, ok := externalClipboard()
+	return nativeClipboard || ok || osc52Available()
+}
+
+func canPaste() bool {
+	_, ok := externalClipboard()
+	return nativeClipboard || ok
+}
+
+// osc52Sequence asks the terminal to put the text on the clipboard, tmux only passes it on when wrapped
+func osc52Sequence(text string, tmux bool) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
+
+// copyWithTools copies through the system clipboard or an external tool, it reports whether one of them took the text
+func copyWithTools(text string) bool {
+	if nativeClipboard {
+		clipboard.Write(clipboard.FmtText, []byte(text))
+		return true
+	}
+	if tool, ok := externalClipboard(); ok {
+		cmd := exec.Command(tool.copy[0], tool.copy[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		err := cmd.Run()
+		if err == nil {
+			return true
+		}
+		log.Errorf("could not copy with %s\n%v", tool.copy[0], err)
+	}
+	return false
+}
+
+// copyText copies from the command line, where nothing else writes to the terminal
+func copyText(text string) error {
+	if copyWithTools(text) {
+		return nil
+	}
+	if osc52Available() {
+		_, err := os.Stdout.WriteString(osc52Sequence(text, os.Getenv("TMUX") != ""))
+		return err
+	}
+	return err_no_clipboard
+}
+
+// copyCmd copies from the tui, a copy through the terminal goes out with the next frame instead of
+// being written in between the renderer
+func copyCmd(text, copied string) tea.Cmd {
+	if copyWithTools(text) {
+		return showInfo(copied, info_default)
+	}
+	if osc52Available() {
+		return tea.Batch(sendEscape(osc52Sequence(text, os.Getenv("TMUX") != "")), showInfo(copied, info_default))
+	}
+	return showInfo(err_no_clipboard.Error(), info_error)
+}
+
+// pasteText reads the clipboard, the terminal can't be asked for it so only the tools fill in
+func pasteText() (string, error) {
+	if nativeClipboard {
+		return string(clipboard.Read(clipboard.FmtText)), nil
+	}
+	if tool, ok := externalClipboard(); ok {
+		out, err := exec.Command(tool.paste[0], tool.paste[1:]...).Output()
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	}
+	return "", err_no_paste
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestOSC52Sequence(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		tmux bool
+		want string
+	}{
+		{name: "plain", text: "hello", want: "\x1b]52;c;aGVsbG8=\a"},
+		{name: "empty", text: "", want: "\x1b]52;c;\a"},
+		{name: "multibyte", text: "héllo\n", want: "\x1b]52;c;aMOpbGxvCg==\a"},
+		{name: "tmux", text: "hello", tmux: true, want: "\x1bPtmux;\x1b\x1b]52;c;aGVsbG8=\a\x1b\\"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := osc52Sequence(tt.text, tt.tmux); got != tt.want {
+				t.Errorf("osc52Sequence(%q, %v) = %q, want %q", tt.text, tt.tmux, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOSC52Terminal(t *testing.T) {
+	tests := []struct {
+		name        string
+		term        string
+		termProgram string
+		wtSession   string
+		want        bool
+	}{
+		{name: "unknown terminal", term: "xterm-256color"},
+		{name: "dumb", term: "dumb"},
+		{name: "kitty", term: "xterm-kitty", want: true},
+		{name: "tmux", term: "tmux-256color", want: true},
+		{name: "iterm", term: "xterm-256color", termProgram: "iTerm.app", want: true},
+		{name: "windows terminal", term: "xterm-256color", wtSession: "1", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TERM", tt.term)
+			t.Setenv("TERM_PROGRAM", tt.termProgram)
+			t.Setenv("WT_SESSION", tt.wtSession)
+			if got := osc52Terminal(); got != tt.want {
+				t.Errorf("osc52Terminal = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
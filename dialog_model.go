@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/help"
 	tea "github.com/charmbracelet/bubbletea"
@@ -19,6 +20,18 @@ const (
 	dialog_create
 	dialog_rename
 	dialog_disabled
+	dialog_truncated
+	dialog_review
+	dialog_quit
+	dialog_move
+	dialog_copy
+	dialog_visibility
+	dialog_secrets
+	dialog_transforms
+	dialog_template
+	dialog_language
+	dialog_details
+	dialog_links
 )
 
 type dialogModel struct {
@@ -27,6 +40,10 @@ type dialogModel struct {
 	state  dialogState
 	form   *huh.Form
 	styles Styles
+	// shown above the form, like the diff while reviewing a save
+	review string
+	// variables asked for by the template form
+	variables []templateVariable
 }
 
 type dialogSubmitMsg struct {
@@ -34,6 +51,15 @@ type dialogSubmitMsg struct {
 	gistName       string
 	value          string
 	gistVisibility gistVisibility
+	// id of the gist picked as the target of a move or copy
+	target string
+	// delete the original once a gist got re-created with another visibility
+	deleteOriginal bool
+	// template picked while creating a gist, and the values of its variables
+	template  string
+	variables map[string]string
+	// language picked while creating a file
+	language string
 }
 
 func (m dialogModel) dialogTheme() *huh.Theme {
@@ -99,6 +125,18 @@ func (m *dialogModel) formInput(actionType, value string) *huh.Form {
 			huh.NewOption("Secret", gist_secret),
 		).Key("visibility").WithTheme(m.dialogTheme())
 		fields = append(fields, s)
+
+		templates, err := listTemplates()
+		if err != nil {
+			log.Errorf("could not list templates\n%v", err)
+		}
+		if len(templates) > 0 {
+			options := []huh.Option[string]{huh.NewOption("None", "")}
+			for _, name := range templates {
+				options = append(options, huh.NewOption(name, name))
+			}
+			fields = append(fields, huh.NewSelect[string]().Title("Template").Options(options...).Key("template").WithTheme(m.dialogTheme()))
+		}
 	}
 
 	fields = append(fields, huh.NewConfirm().
@@ -111,10 +149,174 @@ func (m *dialogModel) formInput(actionType, value string) *huh.Form {
 	)
 }
 
-func (m *dialogModel) formDelete() *huh.Form {
+// formFile asks for the language of the new file first, so its name can be suggested
+func (m *dialogModel) formFile() *huh.Form {
+	d := true
+	var language, value string
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Language").
+				Options(languageOptions("Detect from filename")...).
+				Filtering(true).
+				Height(8).
+				Value(&language).
+				Key("language").WithTheme(m.dialogTheme()),
+			huh.NewInput().
+				PlaceholderFunc(func() string {
+					if suggested := suggestFilename("", language); suggested != "" {
+						return suggested
+					}
+					return "Enter File name"
+				}, &language).
+				DescriptionFunc(func() string {
+					if language == "" {
+						return ""
+					}
+					return "The extension of the language is added when the name has none"
+				}, &language).
+				Value(&value).Key("value").WithWidth(60).WithTheme(m.dialogTheme()),
+			huh.NewConfirm().
+				Affirmative("Create").
+				Key("confirm").
+				Negative("Cancel").Value(&d).WithTheme(m.dialogTheme()),
+		),
+	)
+}
+
+// formLanguage picks the language the file is highlighted with, detecting it clears the override
+func (m *dialogModel) formLanguage(filename, current string) *huh.Form {
+	d := true
+	language := current
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title(fmt.Sprintf("Language of %s", filename)).
+				Options(languageOptions("Detect automatically")...).
+				Filtering(true).
+				Height(8).
+				Value(&language).
+				Key("value").WithTheme(m.dialogTheme()),
+			huh.NewConfirm().
+				Affirmative("Set").
+				Key("confirm").
+				Negative("Cancel").Value(&d).WithTheme(m.dialogTheme()),
+		),
+	)
+}
+
+// formDetails only shows something, closing it is the only answer
+func (m *dialogModel) formDetails(title, details string) *huh.Form {
+	d := true
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().Title(title).Description(details).Affirmative("Close").Negative("").Key("confirm").Value(&d).WithTheme(m.dialogTheme()),
+		),
+	)
+}
+
+// formLinks picks which link of the gist gets copied
+func (m *dialogModel) formLinks(options []huh.Option[string]) *huh.Form {
+	d := true
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().Title("Copy to clipboard").Options(options...).Key("value").WithTheme(m.dialogTheme()),
+			huh.NewConfirm().Affirmative("Copy").Negative("Cancel").Key("confirm").Value(&d).WithTheme(m.dialogTheme()),
+		),
+	)
+}
+
+// formDelete asks for confirmation, bulk actions describe what they are about to do and can ask for more above it
+func (m *dialogModel) formDelete(description string, fields ...huh.Field) *huh.Form {
+	fields = append(fields,
+		huh.NewConfirm().Title("Are you sure?").Description(description).Affirmative("Confirm").Negative("Cancel").Key("confirm").WithTheme(m.dialogTheme()),
+	)
+	form := huh.NewForm(
+		huh.NewGroup(fields...),
+	)
+	return form
+}
+
+// gistPicker selects the gist files get moved or copied to
+func (m *dialogModel) gistPicker(options []huh.Option[string]) huh.Field {
+	return huh.NewSelect[string]().Title("Target gist").Options(options...).Key("target").WithTheme(m.dialogTheme())
+}
+
+// formVisibility picks the new visibility, published gists get re-created and can have their original deleted
+func (m *dialogModel) formVisibility(current gistVisibility, published int, description string) *huh.Form {
+	visibility := gist_public
+	if current == gist_public {
+		visibility = gist_secret
+	}
+	fields := []huh.Field{
+		huh.NewSelect[gistVisibility]().Title("Gist Visiblity").Options(
+			huh.NewOption("Public", gist_public),
+			huh.NewOption("Secret", gist_secret),
+		).Value(&visibility).Key("visibility").WithTheme(m.dialogTheme()),
+	}
+	if published > 0 {
+		description += "\nGithub can't change it in place, published gists get re-created\nand their urls change."
+		fields = append(fields, huh.NewConfirm().
+			Title(fmt.Sprintf("Delete the original of %d published gist(s)?", published)).
+			Affirmative("Delete").
+			Negative("Keep").
+			Key("deleteOriginal").WithTheme(m.dialogTheme()))
+	}
+	return m.formDelete(description, fields...)
+}
+
+// formSecrets asks whether possible secrets get redacted before the upload or save goes through
+func (m *dialogModel) formSecrets(count int, verb string) *huh.Form {
+	return m.formDelete(
+		fmt.Sprintf("%d possible secret(s) are about to reach Github", count),
+		huh.NewSelect[string]().Title("Possible secrets").Options(
+			huh.NewOption(fmt.Sprintf("Redact, then %s", verb), "redact"),
+			huh.NewOption(fmt.Sprintf("%s anyway", strings.ToUpper(verb[:1])+verb[1:]), "ignore"),
+		).Key("value").WithTheme(m.dialogTheme()),
+	)
+}
+
+// formTransforms asks whether the transformed content is what gets uploaded or saved
+func (m *dialogModel) formTransforms(files int, verb string) *huh.Form {
+	return m.formDelete(
+		fmt.Sprintf("The transforms changed %d file(s)", files),
+		huh.NewSelect[string]().Title("Transformed content").Options(
+			huh.NewOption(fmt.Sprintf("Apply the changes, then %s", verb), "apply"),
+			huh.NewOption(fmt.Sprintf("%s unchanged", strings.ToUpper(verb[:1])+verb[1:]), "skip"),
+		).Key("value").WithTheme(m.dialogTheme()),
+	)
+}
+
+// formTemplate asks for the variables of the template, filled in with their defaults
+func (m *dialogModel) formTemplate(t *gistTemplate) *huh.Form {
+	m.variables = t.Variables
+	fields := []huh.Field{}
+	for _, v := range t.Variables {
+		value := v.Default
+		fields = append(fields, huh.NewInput().Title(v.title()).Value(&value).Key("var_"+v.Name).WithWidth(60).WithTheme(m.dialogTheme()))
+	}
+	d := true
+	fields = append(fields, huh.NewConfirm().
+		Title(fmt.Sprintf("Create from %s", t.name)).
+		Affirmative("Create").
+		Negative("Cancel").
+		Key("confirm").Value(&d).WithTheme(m.dialogTheme()))
+	return huh.NewForm(huh.NewGroup(fields...))
+}
+
+func (m *dialogModel) formConfirm(title, description, affirm string) *huh.Form {
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().Title(title).Description(description).Affirmative(affirm).Negative("Cancel").Key("confirm").WithTheme(m.dialogTheme()),
+		),
+	)
+	return form
+}
+
+func (m *dialogModel) formReview(filename string) *huh.Form {
 	form := huh.NewForm(
 		huh.NewGroup(
-			huh.NewConfirm().Title("Are you sure?").Affirmative("Confirm").Negative("Cancel").Key("confirm").WithTheme(m.dialogTheme()),
+			huh.NewConfirm().Title(fmt.Sprintf("Save changes to %s?", filename)).Affirmative("Save").Negative("Discard").Key("confirm").WithTheme(m.dialogTheme()),
 		),
 	)
 	return form
@@ -126,6 +328,18 @@ const (
 	form_type_create formType = iota
 	form_type_delete
 	form_type_rename
+	form_type_truncated
+	form_type_review
+	form_type_quit
+	form_type_move
+	form_type_copy
+	form_type_visibility
+	form_type_secrets
+	form_type_transforms
+	form_type_template
+	form_type_language
+	form_type_details
+	form_type_links
 )
 
 func newDialogModel(width, height int, state dialogState, client *github.Client) dialogModel {
@@ -175,6 +389,22 @@ func (m dialogModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				visGet := m.form.Get("visibility")
 				visibility, _ := visGet.(gistVisibility)
 				msg.gistVisibility = visibility
+				msg.template = m.form.GetString("template")
+				msg.language = m.form.GetString("language")
+			}
+			if m.state == dialog_template {
+				msg.variables = map[string]string{}
+				for _, v := range m.variables {
+					msg.variables[v.Name] = m.form.GetString("var_" + v.Name)
+				}
+			}
+			if m.state == dialog_move || m.state == dialog_copy {
+				msg.target = m.form.GetString("target")
+			}
+			if m.state == dialog_visibility {
+				visibility, _ := m.form.Get("visibility").(gistVisibility)
+				msg.gistVisibility = visibility
+				msg.deleteOriginal = m.form.GetBool("deleteOriginal")
 			}
 
 			cmds = append(cmds, func() tea.Msg {
@@ -194,6 +424,9 @@ func (m dialogModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m dialogModel) View() string {
 	formView := m.form.View()
+	if m.review != "" {
+		formView = lipgloss.JoinVertical(lipgloss.Left, m.review, "", formView)
+	}
 	styledContainer := m.styles.Dialog.Container.Render(formView)
 	return lipgloss.Place(
 		m.width,
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquilax/truncate"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type diffOp int
+
+const (
+	diff_equal diffOp = iota
+	diff_delete
+	diff_insert
+)
+
+type diffLine struct {
+	op   diffOp
+	text string
+}
+
+// past this many cells the lcs table gets too large, the changed middle is then shown as replaced entirely
+const maxDiffCells = 4_000_000
+
+// lines of unchanged content shown around every change
+const diffContext = 2
+
+// diffLines compares two texts line by line
+func diffLines(a, b string) []diffLine {
+	before, after := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// strip the common prefix and suffix, edits usually touch a small part of a file
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	var lines []diffLine
+	for _, l := range before[:prefix] {
+		lines = append(lines, diffLine{diff_equal, l})
+	}
+	lines = append(lines, diffMiddle(before[prefix:len(before)-suffix], after[prefix:len(after)-suffix])...)
+	for _, l := range before[len(before)-suffix:] {
+		lines = append(lines, diffLine{diff_equal, l})
+	}
+	return lines
+}
+
+func diffMiddle(a, b []string) []diffLine {
+	var lines []diffLine
+	if len(a)*len(b) > maxDiffCells {
+		for _, l := range a {
+			lines = append(lines, diffLine{diff_delete, l})
+		}
+		for _, l := range b {
+			lines = append(lines, diffLine{diff_insert, l})
+		}
+		return lines
+	}
+
+	// lcs[i][j] holds the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{diff_equal, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{diff_delete, a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{diff_insert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{diff_delete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{diff_insert, b[j]})
+	}
+	return lines
+}
+
+// diffRow is a line of the side by side view, a zero line number leaves that side empty
+type diffRow struct {
+	left, right       string
+	leftNum, rightNum int
+	changed           bool
+}
+
+// pairRows lines up deleted lines with the inserted lines that replaced them
+func pairRows(lines []diffLine) []diffRow {
+	var rows []diffRow
+	leftNum, rightNum := 0, 0
+	for i := 0; i < len(lines); {
+		if lines[i].op == diff_equal {
+			leftNum++
+			rightNum++
+			rows = append(rows, diffRow{left: lines[i].text, right: lines[i].text, leftNum: leftNum, rightNum: rightNum})
+			i++
+			continue
+		}
+
+		var deleted, inserted []string
+		for ; i < len(lines) && lines[i].op != diff_equal; i++ {
+			if lines[i].op == diff_delete {
+				deleted = append(deleted, lines[i].text)
+			} else {
+				inserted = append(inserted, lines[i].text)
+			}
+		}
+		for k := range max(len(deleted), len(inserted)) {
+			row := diffRow{changed: true}
+			if k < len(deleted) {
+				leftNum++
+				row.left, row.leftNum = deleted[k], leftNum
+			}
+			if k < len(inserted) {
+				rightNum++
+				row.right, row.rightNum = inserted[k], rightNum
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func hasChanges(lines []diffLine) bool {
+	for _, l := range lines {
+		if l.op != diff_equal {
+			return true
+		}
+	}
+	return false
+}
+
+// renderSideBySide shows the changed rows of the diff with a few rows of context around them,
+// the old text on the left and the new text on the right
+func renderSideBySide(lines []diffLine, width, height int, styles DiffStyle) string {
+	rows := pairRows(lines)
+
+	keep := make([]bool, len(rows))
+	for i, row := range rows {
+		if !row.changed {
+			continue
+		}
+		for k := max(0, i-diffContext); k <= min(len(rows)-1, i+diffContext); k++ {
+			keep[k] = true
+		}
+	}
+
+	gutter := 5
+	column := max((width-3)/2-gutter, 10)
+	side := func(text string, num int, style lipgloss.Style) string {
+		numStr := ""
+		if num > 0 {
+			numStr = fmt.Sprint(num)
+		}
+		text = truncate.Truncate(strings.ReplaceAll(text, "\t", "    "), column, "…", truncate.PositionEnd)
+		return styles.Gutter.Width(gutter).Render(numStr+" ") + style.Width(column).MaxHeight(1).Render(text)
+	}
+
+	var out []string
+	skipped := false
+	for i, row := range rows {
+		if !keep[i] {
+			skipped = true
+			continue
+		}
+		if skipped && len(out) > 0 {
+			out = append(out, styles.Gutter.Render("⋯"))
+		}
+		skipped = false
+
+		left, right := styles.Unchanged, styles.Unchanged
+		if row.changed {
+			left, right = styles.Deleted, styles.Inserted
+		}
+		out = append(out, side(row.left, row.leftNum, left)+styles.Gutter.Render(" │ ")+side(row.right, row.rightNum, right))
+	}
+
+	if height > 0 && len(out) > height {
+		hidden := len(out) - height + 1
+		out = append(out[:height-1], styles.Gutter.Render(fmt.Sprintf("… %d more line(s)", hidden)))
+	}
+	return strings.Join(out, "\n")
+}
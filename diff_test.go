@@ -0,0 +1,90 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// ops renders a diff compactly, " " for equal, "-" for deleted and "+" for inserted lines
+func ops(lines []diffLine) []string {
+	prefix := map[diffOp]string{diff_equal: " ", diff_delete: "-", diff_insert: "+"}
+	var out []string
+	for _, l := range lines {
+		out = append(out, prefix[l.op]+l.text)
+	}
+	return out
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{name: "equal", a: "a\nb", b: "a\nb", want: []string{" a", " b"}},
+		{name: "both empty", a: "", b: "", want: []string{" "}},
+		{name: "from empty", a: "", b: "a", want: []string{"-", "+a"}},
+		{name: "append", a: "a\nb", b: "a\nb\nc", want: []string{" a", " b", "+c"}},
+		{name: "prepend", a: "b\nc", b: "a\nb\nc", want: []string{"+a", " b", " c"}},
+		{name: "remove middle", a: "a\nb\nc", b: "a\nc", want: []string{" a", "-b", " c"}},
+		{name: "replace middle", a: "a\nb\nc", b: "a\nx\nc", want: []string{" a", "-b", "+x", " c"}},
+		{
+			name: "common lines between changes",
+			a:    "head\none\nkeep\ntwo\ntail",
+			b:    "head\n1\nkeep\n2\ntail",
+			want: []string{" head", "-one", "+1", " keep", "-two", "+2", " tail"},
+		},
+		{
+			name: "moved line",
+			a:    "a\nb\nc\nd",
+			b:    "a\nc\nb\nd",
+			want: []string{" a", "-b", " c", "+b", " d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ops(diffLines(tt.a, tt.b)); !slices.Equal(got, tt.want) {
+				t.Errorf("diffLines(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesTooLarge(t *testing.T) {
+	// past the cell limit the changed middle is replaced as a whole, it still has to hold every line
+	n := 2001
+	before, after := make([]string, n), make([]string, n)
+	for i := range n {
+		before[i] = "old " + strings.Repeat("x", i%7)
+		after[i] = "new " + strings.Repeat("x", i%7)
+	}
+	lines := diffLines(strings.Join(before, "\n"), strings.Join(after, "\n"))
+	if len(lines) != 2*n {
+		t.Fatalf("got %d lines, want %d", len(lines), 2*n)
+	}
+	for i, l := range lines {
+		want := diff_delete
+		if i >= n {
+			want = diff_insert
+		}
+		if l.op != want {
+			t.Fatalf("line %d has op %d, want %d", i, l.op, want)
+		}
+	}
+}
+
+func TestPairRows(t *testing.T) {
+	rows := pairRows(diffLines("a\nb\nc\nd", "a\nx\nd\ne"))
+	want := []diffRow{
+		{left: "a", right: "a", leftNum: 1, rightNum: 1},
+		{left: "b", right: "x", leftNum: 2, rightNum: 2, changed: true},
+		{left: "c", leftNum: 3, changed: true},
+		{left: "d", right: "d", leftNum: 4, rightNum: 3},
+		{right: "e", rightNum: 4, changed: true},
+	}
+	if !slices.Equal(rows, want) {
+		t.Errorf("pairRows = %+v, want %+v", rows, want)
+	}
+}
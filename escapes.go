@@ -0,0 +1,60 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// how long queued escapes stay part of the view, the renderer flushes several frames in that time
+const escapeFrameTime = 100 * time.Millisecond
+
+// escapeMsg carries an escape sequence that has to reach the terminal once, like an image or a
+// clipboard write. writing it to stdout directly would interleave it with the renderer
+type escapeMsg string
+
+type escapesSentMsg struct {
+	gen int
+}
+
+func sendEscape(seq string) tea.Cmd {
+	return func() tea.Msg {
+		return escapeMsg(seq)
+	}
+}
+
+// queueEscape puts the sequence in front of the next frames, it is dropped again once the
+// renderer had the time to flush one of them
+func (m *model) queueEscape(seq string) tea.Cmd {
+	if seq == "" {
+		return nil
+	}
+	m.escapes += seq
+	m.escapesGen++
+	gen := m.escapesGen
+	return tea.Tick(escapeFrameTime, func(time.Time) tea.Msg {
+		return escapesSentMsg{gen: gen}
+	})
+}
+
+// syncImage transmits the kitty image of the previewed file once and deletes it as soon as the
+// preview is left, the frames in between only place it
+func (m *model) syncImage() tea.Cmd {
+	want := 0
+	if m.screenState == mainScreen && m.mainScreen.editorBinary && m.mainScreen.imageData != "" {
+		want = m.mainScreen.imageRev
+	}
+	if want == m.imageShown {
+		return nil
+	}
+
+	var seq string
+	if m.imageShown != 0 {
+		seq += deleteKittyImage()
+	}
+	if want != 0 {
+		seq += m.mainScreen.imageData
+	}
+	m.imageShown = want
+	return m.queueEscape(seq)
+}
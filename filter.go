@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type gistFilter int
+
+const (
+	gist_filter_all gistFilter = iota
+	gist_filter_public
+	gist_filter_secret
+	gist_filter_draft
+)
+
+func (f gistFilter) String() string {
+	switch f {
+	case gist_filter_public:
+		return "Public"
+	case gist_filter_secret:
+		return "Secret"
+	case gist_filter_draft:
+		return "Draft"
+	}
+	return "All"
+}
+
+func (f gistFilter) next() gistFilter {
+	return (f + 1) % (gist_filter_draft + 1)
+}
+
+// matches tells whether the filter shows the gist, public and secret only cover published gists
+// since drafts are not visible to anyone yet
+func (f gistFilter) matches(g *gist) bool {
+	switch f {
+	case gist_filter_public:
+		return g.status == gist_status_published && g.visiblity == gist_public
+	case gist_filter_secret:
+		return g.status == gist_status_published && g.visiblity == gist_secret
+	case gist_filter_draft:
+		return g.status == gist_status_drafted
+	}
+	return true
+}
+
+func gistListTitle(filter gistFilter) string {
+	title := "Gists"
+	if filter != gist_filter_all {
+		title += " · " + filter.String()
+	}
+	// pad the title so the title bar spans the whole pane
+	return fmt.Sprintf("%-36s", title)
+}
+
+// sortedGists returns the gists sorted alphabetically
+func sortedGists(gists map[*gist][]list.Item) []*gist {
+	sorted := slices.Collect(maps.Keys(gists))
+	slices.SortFunc(sorted, func(a, b *gist) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return sorted
+}
+
+// filteredGists returns the gists the current filter shows, sorted alphabetically
+func (m *mainModel) filteredGists() []list.Item {
+	items := []list.Item{}
+	for _, g := range sortedGists(m.gists) {
+		if m.gistFilter.matches(g) {
+			items = append(items, g)
+		}
+	}
+	return items
+}
+
+// applyGistFilter fills the gist list with the gists the filter shows, keeping the selection when it still shows
+func (m *mainModel) applyGistFilter() []tea.Cmd {
+	var cmds []tea.Cmd
+	selected := m.selectedGist()
+	m.gistList.Title = gistListTitle(m.gistFilter)
+	cmds = append(cmds, m.gistList.SetItems(m.filteredGists()))
+
+	idx := m.gistIndex(selected)
+	if idx < 0 {
+		idx = 0
+	}
+	m.gistList.Select(idx)
+
+	files := []list.Item{}
+	if g := m.selectedGist(); g != nil {
+		files = m.gists[g]
+	}
+	if m.selectedGist() != selected {
+		m.marks.clearFiles()
+		m.fileList.Select(0)
+	}
+	cmds = append(cmds, m.fileList.SetItems(files))
+	_, updateFileList := m.fileList.Update(nil)
+	return append(cmds, updateFileList)
+}
+
+// toggleGistFilter cycles through showing every gist, only public, only secret and only drafted gists
+func (m *mainModel) toggleGistFilter() []tea.Cmd {
+	m.gistFilter = m.gistFilter.next()
+	// hidden gists must not be part of a bulk action
+	clear(m.marks.gists)
+	cmds := m.applyGistFilter()
+
+	info := fmt.Sprintf("showing %s gists", strings.ToLower(m.gistFilter.String()))
+	if n := len(m.gistList.Items()); n == 0 {
+		info = fmt.Sprintf("no %s gists", strings.ToLower(m.gistFilter.String()))
+	}
+	return append(cmds, showInfo(info, info_default))
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v74/github"
+)
+
+// remote every gist mirror is cloned from, a local bare repository can be swapped in for testing
+var gistRemoteFormat = "https://gist.github.com/%s.git"
+
+func gistRemote(gistId string) string {
+	return fmt.Sprintf(gistRemoteFormat, gistId)
+}
+
+// local clone of a gist lives next to the database inside the config folder
+func mirrorPath(gistId string) string {
+	return filepath.Join(cfg.ConfigPath, "mirrors", gistId)
+}
+
+// runGit runs git with the access token passed as an extra header. the header goes through the
+// environment, on the command line every user could read it from the process list, and a remote
+// url would keep it on disk
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + cfg.AccessToken))
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		// never hang waiting for credentials the tui cannot prompt for
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth,
+	)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v\n%s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// requests run concurrently, so every mirror gets a lock to keep them from stepping on each other
+var mirrorLocks sync.Map
+
+func lockMirror(gistId string) func() {
+	mu, _ := mirrorLocks.LoadOrStore(gistId, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// syncMirror clones the gist into its mirror, or updates the mirror to the latest revision when it already exists
+func syncMirror(ctx context.Context, gistId string) (string, error) {
+	dir := mirrorPath(gistId)
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		if _, err := runGit(ctx, dir, "fetch", "origin"); err != nil {
+			return "", err
+		}
+		if _, err := runGit(ctx, dir, "reset", "--hard", "@{upstream}"); err != nil {
+			return "", err
+		}
+		return dir, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return "", err
+	}
+	if _, err := runGit(ctx, "", "clone", gistRemote(gistId), dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// readFromMirror reads the full content of a gist file from the mirror, used when the api and raw url cannot serve it
+func readFromMirror(ctx context.Context, gistId, filename string) (string, error) {
+	defer lockMirror(gistId)()
+
+	dir, err := syncMirror(ctx, gistId)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, filepath.Base(filename)))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// gitChange is a set of file operations applied to a gist mirror as a single commit
+type gitChange struct {
+	// filename to content of files to create or overwrite
+	write map[string][]byte
+	// old filename to new filename
+	rename  map[string]string
+	remove  []string
+	message string
+}
+
+// pushChange commits the change on top of the latest revision of the gist and pushes it
+func pushChange(ctx context.Context, gistId string, change gitChange) error {
+	defer lockMirror(gistId)()
+
+	dir, err := syncMirror(ctx, gistId)
+	if err != nil {
+		return err
+	}
+
+	for from, to := range change.rename {
+		if _, err := runGit(ctx, dir, "mv", "--", filepath.Base(from), filepath.Base(to)); err != nil {
+			return err
+		}
+	}
+	for filename, content := range change.write {
+		filename = filepath.Base(filename)
+		if err := os.WriteFile(filepath.Join(dir, filename), content, 0644); err != nil {
+			return err
+		}
+		if _, err := runGit(ctx, dir, "add", "--", filename); err != nil {
+			return err
+		}
+	}
+	for _, filename := range change.remove {
+		if _, err := runGit(ctx, dir, "rm", "--", filepath.Base(filename)); err != nil {
+			return err
+		}
+	}
+
+	// nothing changed, so there is nothing to push
+	if _, err := runGit(ctx, dir, "diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+
+	args := []string{"commit", "-m", change.message}
+	// mirrors live outside of any repo the user configured, so make sure the commit has an author
+	if name, _ := runGit(ctx, dir, "config", "user.name"); name == "" {
+		args = append([]string{"-c", "user.name=gisting", "-c", "user.email=gisting@users.noreply.github.com"}, args...)
+	}
+	if _, err := runGit(ctx, dir, args...); err != nil {
+		return err
+	}
+
+	if _, err := runGit(ctx, dir, "push", "origin", "HEAD"); err != nil {
+		// drop the local commit so the next change starts from the remote again
+		runGit(ctx, dir, "reset", "--hard", "@{upstream}")
+		return err
+	}
+	return nil
+}
+
+// pushGistChange pushes the change through the mirror and returns the gist as the api sees it afterwards,
+// so callers get the same response they would get from editing the gist through the api
+func pushGistChange(ctx context.Context, client *github.Client, gistId string, change gitChange) (*github.Gist, error) {
+	if err := pushChange(ctx, gistId, change); err != nil {
+		return nil, err
+	}
+	g, _, err := client.Gists.Get(ctx, gistId)
+	return g, err
+}
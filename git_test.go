@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v74/github"
+)
+
+// git runs git for the test itself, outside of the token handling of runGit
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commitFile writes the file in a clone of the remote and pushes it, like an edit made on github
+func commitFile(t *testing.T, dir, filename, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, filename), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "add", filename)
+	git(t, dir, "commit", "-m", "edit "+filename)
+	git(t, dir, "push", "origin", "HEAD")
+}
+
+func TestGitMirror(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, env := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(env, "gisting")
+	}
+	for _, env := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(env, "gisting@example.com")
+	}
+
+	const gistId = "aa5a315d61ae9438b18d"
+	remotes := t.TempDir()
+	remote := filepath.Join(remotes, gistId+".git")
+	git(t, remotes, "init", "--bare", remote)
+
+	// somebody else's clone, standing in for edits made on github
+	other := filepath.Join(t.TempDir(), "other")
+	git(t, "", "clone", remote, other)
+	commitFile(t, other, "hello.txt", "one")
+
+	previous := gistRemoteFormat
+	gistRemoteFormat = filepath.Join(remotes, "%s.git")
+	t.Cleanup(func() { gistRemoteFormat = previous })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id": %q}`, gistId)
+	}))
+	defer server.Close()
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	ctx := context.Background()
+
+	// clone
+	content, err := readFromMirror(ctx, gistId, "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "one" {
+		t.Errorf("cloned content = %q, want %q", content, "one")
+	}
+
+	// push
+	g, err := pushGistChange(ctx, client, gistId, gitChange{
+		write:   map[string][]byte{"hello.txt": []byte("two"), "new.txt": []byte("new")},
+		message: "Update files",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.GetID() != gistId {
+		t.Errorf("gist id = %q, want %q", g.GetID(), gistId)
+	}
+	for filename, want := range map[string]string{"hello.txt": "two", "new.txt": "new"} {
+		if got := git(t, remote, "show", "HEAD:"+filename); got != want {
+			t.Errorf("pushed %s = %q, want %q", filename, got, want)
+		}
+	}
+
+	// pull
+	git(t, other, "pull")
+	commitFile(t, other, "hello.txt", "three")
+	content, err = readFromMirror(ctx, gistId, "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "three" {
+		t.Errorf("pulled content = %q, want %q", content, "three")
+	}
+}
@@ -3,16 +3,28 @@ package main
 import "github.com/charmbracelet/bubbles/key"
 
 type Keymap struct {
-	Navigate key.Binding
-	Create   key.Binding
-	Upload   key.Binding
-	Delete   key.Binding
-	Rename   key.Binding
-	Copy     key.Binding
-	Left     key.Binding
-	Right    key.Binding
-	Quit     key.Binding
-	Help     key.Binding
+	Navigate   key.Binding
+	Create     key.Binding
+	Upload     key.Binding
+	UploadAll  key.Binding
+	Delete     key.Binding
+	Rename     key.Binding
+	Copy       key.Binding
+	Mark       key.Binding
+	Move       key.Binding
+	CopyTo     key.Binding
+	Visibility key.Binding
+	Filter     key.Binding
+	Language   key.Binding
+	Details    key.Binding
+	Open       key.Binding
+	CopyLink   key.Binding
+	Cancel     key.Binding
+	Preview    key.Binding
+	Left       key.Binding
+	Right      key.Binding
+	Quit       key.Binding
+	Help       key.Binding
 }
 
 func (k Keymap) ShortHelp() []key.Binding {
@@ -22,9 +34,10 @@ func (k Keymap) ShortHelp() []key.Binding {
 func (k Keymap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Navigate, k.Left, k.Right},
-		{k.Create, k.Upload, k.Delete},
-		{k.Rename, k.Copy, k.Help},
-		{k.Quit},
+		{k.Create, k.Upload, k.UploadAll, k.Delete},
+		{k.Rename, k.Copy, k.Language, k.Details, k.Help},
+		{k.Mark, k.Move, k.CopyTo, k.Visibility, k.Filter},
+		{k.Open, k.CopyLink, k.Preview, k.Cancel, k.Quit},
 	}
 }
 
@@ -49,6 +62,10 @@ var DefaultKeymap = Keymap{
 		key.WithKeys("u"),
 		key.WithHelp("u", "upload"),
 	),
+	UploadAll: key.NewBinding(
+		key.WithKeys("U"),
+		key.WithHelp("U", "upload all"),
+	),
 	Delete: key.NewBinding(
 		key.WithKeys("d"),
 		key.WithHelp("d", "delete"),
@@ -61,6 +78,50 @@ var DefaultKeymap = Keymap{
 		key.WithKeys("y"),
 		key.WithHelp("y", "copy content"),
 	),
+	Preview: key.NewBinding(
+		key.WithKeys("ctrl+p"),
+		key.WithHelp("ctrl+p", "markdown preview"),
+	),
+	Mark: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "mark"),
+	),
+	Move: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "move to gist"),
+	),
+	CopyTo: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "copy to gist"),
+	),
+	Visibility: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "visibility"),
+	),
+	Filter: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "filter visibility"),
+	),
+	Language: key.NewBinding(
+		key.WithKeys("L"),
+		key.WithHelp("L", "set language"),
+	),
+	Details: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "file details"),
+	),
+	Open: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "open in browser"),
+	),
+	CopyLink: key.NewBinding(
+		key.WithKeys("Y"),
+		key.WithHelp("Y", "copy link"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("ctrl+x"),
+		key.WithHelp("ctrl+x", "cancel sync"),
+	),
 	Quit: key.NewBinding(
 		key.WithKeys("ctrl+c"),
 		key.WithHelp("ctrl+c", "quit"),
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/ostafen/clover/v2/query"
+)
+
+// content of new files when their language has no boilerplate, github refuses empty files
+const defaultFileContent = "New File"
+
+// boilerplates seeded into new files, keyed by chroma lexer name
+var defaultBoilerplates = map[string]string{
+	"Go":         "package main\n\nfunc main() {\n}\n",
+	"Python":     "def main():\n    pass\n\n\nif __name__ == \"__main__\":\n    main()\n",
+	"Bash":       "#!/usr/bin/env bash\nset -euo pipefail\n",
+	"Rust":       "fn main() {\n}\n",
+	"C":          "#include <stdio.h>\n\nint main(void) {\n    return 0;\n}\n",
+	"HTML":       "<!DOCTYPE html>\n<html>\n<head>\n  <meta charset=\"utf-8\">\n  <title></title>\n</head>\n<body>\n</body>\n</html>\n",
+	"markdown":   "# Title\n",
+	"JavaScript": "\"use strict\";\n",
+}
+
+var extensionGlob = regexp.MustCompile(`^\*(\.[A-Za-z0-9_+\-]+)$`)
+
+// languageOptions lists every language chroma can highlight, sorted by name, after the option to detect it
+func languageOptions(detect string) []huh.Option[string] {
+	names := []string{}
+	for _, lexer := range lexers.GlobalLexerRegistry.Lexers {
+		names = append(names, lexer.Config().Name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+
+	options := []huh.Option[string]{huh.NewOption(detect, "")}
+	for _, name := range slices.Compact(names) {
+		options = append(options, huh.NewOption(name, name))
+	}
+	return options
+}
+
+// lexerExtension returns the first plain extension the lexer is registered for, like .go
+func lexerExtension(lexer chroma.Lexer) string {
+	for _, glob := range lexer.Config().Filenames {
+		if match := extensionGlob.FindStringSubmatch(glob); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// suggestFilename gives the title the extension of the language, unless it already has one
+func suggestFilename(title, language string) string {
+	if language == "" {
+		return title
+	}
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		return title
+	}
+	if title == "" {
+		title = "untitled"
+	}
+	if filepath.Ext(title) != "" || lexers.Match(title) == lexer {
+		return title
+	}
+	return title + lexerExtension(lexer)
+}
+
+// boilerplate returns what a new file of the language starts with, the config can override the defaults
+// by lexer name or alias
+func boilerplate(language string) string {
+	lexer := lexers.Get(language)
+	if language == "" || lexer == nil {
+		return defaultFileContent
+	}
+	config := lexer.Config()
+	for _, name := range append([]string{config.Name}, config.Aliases...) {
+		for key, content := range cfg.Boilerplates {
+			if strings.EqualFold(key, name) {
+				return content
+			}
+		}
+	}
+	if content, ok := defaultBoilerplates[config.Name]; ok {
+		return content
+	}
+	return defaultFileContent
+}
+
+// setLanguage overrides the language of the file, an empty language goes back to detecting it
+func (m *mainModel) setLanguage(g *gist, f file, language string) []tea.Cmd {
+	q := query.NewQuery(string(collectionGistContent)).Where(query.Field("id").Eq(f.id))
+	if err := storage.db.Update(q, map[string]any{"language": language}); err != nil {
+		log.Errorf("could not set the language of %q\n%v", f.title, err)
+		return []tea.Cmd{showInfo("could not update the collection", info_error)}
+	}
+	f.language = language
+	f.detected = ""
+
+	info := fmt.Sprintf("%s is highlighted as %s", f.title, language)
+	if language == "" {
+		info = fmt.Sprintf("detecting the language of %s again", f.title)
+	}
+	cmds := []tea.Cmd{showInfo(info, info_default)}
+
+	if m.editorFile == f.id && !m.editorBinary {
+		lexer := f.lexer(m.editorSaved)
+		if lexer == nil {
+			lexer = lexers.Fallback
+		}
+		f.detected = lexer.Config().Name
+		m.editor.SetLanguage(lexerAlias(lexer), cfg.Theme)
+	}
+	return append(cmds, m.replaceFile(g, f)...)
+}
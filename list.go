@@ -1,16 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/lexers"
 	"github.com/aquilax/truncate"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/dustin/go-humanize"
+	"github.com/ostafen/clover/v2/document"
 	"github.com/ostafen/clover/v2/query"
 )
 
@@ -34,14 +40,30 @@ type gist struct {
 	status    gistStatus     `clover:"status"`
 	visiblity gistVisibility `clover:"visibility"`
 	updatedAt time.Time
+	// the api did not list every file of the gist
+	truncated bool
+	htmlUrl   string
 }
 
 func (f gist) FilterValue() string {
 	return f.name
 }
 
+// htmlURL is the page of the gist on github, drafts don't have one yet
+func (g *gist) htmlURL() string {
+	if g.status == gist_status_drafted {
+		return ""
+	}
+	if g.htmlUrl != "" {
+		return g.htmlUrl
+	}
+	return "https://gist.github.com/" + g.id
+}
+
 type gistsDelegate struct {
 	styles GistsBaseStyle
+	syncs  *syncTracker
+	marks  *marks
 }
 
 func (d gistsDelegate) Height() int {
@@ -56,9 +78,9 @@ func (d gistsDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd {
 	return nil
 }
 
-func newGistList(items []list.Item, styles GistsBaseStyle) list.Model {
-	l := list.New(items, gistsDelegate{styles: styles}, 45, 0)
-	l.Title = "Gists                               " // THIS I STILL DONT KNOW HOW TO FIX LOL
+func newGistList(items []list.Item, styles GistsBaseStyle, syncs *syncTracker, marks *marks) list.Model {
+	l := list.New(items, gistsDelegate{styles: styles, syncs: syncs, marks: marks}, 45, 0)
+	l.Title = gistListTitle(gist_filter_all)
 	l.SetShowStatusBar(false)
 	l.SetShowHelp(false)
 	l.Styles.Title = styles.Title
@@ -76,13 +98,26 @@ func (d gistsDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 
 	var label string
 
+	bullet := "→ "
+	if d.marks.isGistMarked(g) {
+		bullet = "✓ "
+	}
+
 	if g.status == gist_status_drafted {
 		// truncate *only* the name, then append (Draft)
 		truncated := truncate.Truncate(g.name, 25, "...", truncate.PositionEnd)
-		label = "→ " + truncated + " (Draft)"
+		label = bullet + truncated + " (Draft)"
 	} else {
 		truncated := truncate.Truncate(g.name, 30, "...", truncate.PositionEnd)
-		label = "→ " + truncated
+		label = bullet + truncated
+	}
+
+	if g.truncated {
+		label += " (Truncated)"
+	}
+
+	if d.syncs.isSyncing(g.id) {
+		label += " " + d.syncs.badge()
 	}
 
 	style := d.styles.Unselected
@@ -90,10 +125,16 @@ func (d gistsDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 		style = d.styles.Selected
 	}
 
+	// public gists stand out so accidentally published ones are easy to catch
+	badge := d.styles.Secret.Render("\uf023")
+	if g.visiblity == gist_public {
+		badge = d.styles.Public.Render("\uf0ac")
+	}
+
 	attribute := d.styles.Unselected
 	lastUpdated := fmt.Sprintf("Last updated: %s", humanize.Time(g.updatedAt))
 
-	fmt.Fprint(w, "  "+style.Render(label)+"\n    "+attribute.Render(lastUpdated))
+	fmt.Fprint(w, "  "+style.Render(label)+" "+badge+"\n    "+attribute.Render(lastUpdated))
 }
 
 type file struct {
@@ -105,30 +146,157 @@ type file struct {
 	updatedAt string `clover:"updatedAt"`
 	content   string `clover:"content"`
 	draft     bool   `clover:"draft"`
-	stale     bool
+	size      int    `clover:"size"`
+	// the editor buffer has changes that are not saved yet, they are kept as a local draft
+	dirty bool
+	// language picked by the user, it wins over whatever gets detected
+	language string `clover:"language"`
+	// language analysed from the content once it was loaded
+	detected string
+	// counted once the content was loaded, published files only have it then
+	lines  int
+	binary bool
+	// only part of the content could be loaded, it must not be uploaded like that
+	truncated bool
 }
 
 func (f file) Title() string       { return f.title }
 func (f file) Description() string { return f.desc }
 func (f file) FilterValue() string { return f.title }
 
-func (f file) getContent() (string, error) {
+// lexer picks the highlighting of the file, the override first, then the filename and the content last
+func (f file) lexer(content string) chroma.Lexer {
+	if f.language != "" {
+		if lexer := lexers.Get(f.language); lexer != nil {
+			return lexer
+		}
+	}
+	if lexer := lexers.Match(f.title); lexer != nil {
+		return lexer
+	}
+	return lexers.Analyse(content)
+}
+
+// languageName is the language shown in the file list, empty until it is known
+func (f file) languageName() string {
+	if f.language != "" {
+		return f.language
+	}
+	if f.detected != "" {
+		return f.detected
+	}
+	if lexer := lexers.Match(f.title); lexer != nil {
+		return lexer.Config().Name
+	}
+	return ""
+}
+
+// lineCount counts the lines of the content, a trailing newline does not start another one
+func lineCount(content string) int {
+	if content == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+}
+
+// sizeLabel is the size of the file, drafts are as big as their content
+func (f file) sizeLabel() string {
+	size := f.size
+	if size == 0 {
+		size = len(f.content)
+	}
+	return humanize.Bytes(uint64(size))
+}
+
+// linesLabel is empty while the content of the file is not loaded
+func (f file) linesLabel() string {
+	switch {
+	case f.binary:
+		return "binary"
+	case f.lines > 0:
+		return fmt.Sprintf("%d lines", f.lines)
+	case f.content != "":
+		return fmt.Sprintf("%d lines", lineCount(f.content))
+	}
+	return ""
+}
+
+// modified parses updatedAt, which is stored the way time.Time prints itself
+func (f file) modified() (time.Time, bool) {
+	value, _, _ := strings.Cut(f.updatedAt, " m=")
+	t, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", value)
+	return t, err == nil
+}
+
+// revision is the commit of the gist the raw url points at
+func (f file) revision() string {
+	u, err := url.Parse(f.rawUrl)
+	if err != nil {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if idx := slices.Index(segments, "raw"); idx >= 0 && idx+1 < len(segments) {
+		return segments[idx+1]
+	}
+	return ""
+}
+
+// details lists everything known about the file, for the detail popup
+func (f file) details(g *gist) string {
+	rows := [][2]string{{"Gist", g.name}}
+	if language := f.languageName(); language != "" {
+		rows = append(rows, [2]string{"Language", language})
+	}
+	size := f.sizeLabel()
+	if lines := f.linesLabel(); lines != "" {
+		size += ", " + lines
+	}
+	rows = append(rows, [2]string{"Size", size})
+	if updatedAt, ok := f.modified(); ok {
+		rows = append(rows, [2]string{"Updated", fmt.Sprintf("%s (%s)", updatedAt.Format("2006-01-02 15:04"), humanize.Time(updatedAt))})
+	}
 	if f.draft {
-		return f.content, nil
+		rows = append(rows, [2]string{"Status", "Draft, not uploaded yet"})
+	} else {
+		rows = append(rows,
+			[2]string{"Raw URL", f.rawUrl},
+			[2]string{"Gist URL", g.htmlURL()},
+			[2]string{"Revision", f.revision()},
+		)
 	}
 
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, fmt.Sprintf("%-9s %s", row[0]+":", row[1]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lexerAlias is the name the editor knows the language by
+func lexerAlias(lexer chroma.Lexer) string {
+	if len(lexer.Config().Aliases) > 0 {
+		return lexer.Config().Aliases[0]
+	}
+	return lexer.Config().Name
+}
+
+// raw urls are served outside of the api, so they dont need the retrying client
+var rawClient = &http.Client{Timeout: 5 * time.Second}
+
+// cachedContent finds the stored document of a published file and whether its content is missing or stale
+func (f file) cachedContent() (*document.Document, bool, error) {
 	existing, err := storage.db.FindFirst(
 		query.NewQuery(string(collectionGistContent)).Where(query.Field("rawUrl").Eq(f.rawUrl).And(query.Field("id").Eq(f.id))),
 	)
 
 	if err != nil {
 		log.Errorln(err)
-		return "", err
+		return nil, false, err
 	}
 
 	if existing == nil {
 		log.Errorf("Could not find %q with id %q and rawUrl %q\n", f.title, f.id, f.rawUrl)
-		return "", nil
+		return nil, false, nil
 	}
 
 	var shouldFetch bool
@@ -139,45 +307,92 @@ func (f file) getContent() (string, error) {
 		shouldFetch = true
 	}
 
-	if shouldFetch {
-		client := &http.Client{Timeout: 5 * time.Second}
-		resp, err := client.Get(f.rawUrl)
+	return existing, shouldFetch, nil
+}
+
+// fetchContent downloads the file and stores the content in the file document, truncated is
+// true when only part of the file could be downloaded. it runs outside of the update loop, so
+// only the content is written back and edits made in the meantime are left alone
+func (f file) fetchContent() (content string, truncated bool, err error) {
+	if f.size <= rawUrlLimit {
+		content, err = f.fetchRaw()
 		if err != nil {
-			log.Errorf("Could not fetch file with raw url: %s", f.rawUrl)
-			return "", err
+			return "", false, err
 		}
-		defer resp.Body.Close()
+	}
 
-		contentBytes, err := io.ReadAll(resp.Body)
+	// the raw url gave up on the file, only a clone of the gist has all of it
+	if len(content) < f.size {
+		full, err := readFromMirror(context.Background(), f.gistId, f.title)
 		if err != nil {
-			log.Errorf(err.Error())
-			return "", err
+			log.Errorf("could not read %q from the gist mirror\n%v", f.title, err)
+			truncated = true
+		} else {
+			content = full
 		}
+	}
 
-		content := string(contentBytes)
+	q := query.NewQuery(string(collectionGistContent)).Where(query.Field("id").Eq(f.id).And(query.Field("rawUrl").Eq(f.rawUrl)))
+	if err := storage.db.Update(q, map[string]any{"content": content, "truncated": truncated}); err != nil {
+		log.Errorln(err)
+		return "", false, err
+	}
 
-		existing.Set("content", content)
+	return content, truncated, nil
+}
 
-		if err := storage.db.Save(string(collectionGistContent), existing); err != nil {
-			log.Errorf(err.Error())
-			return "", err
-		}
+func (f file) fetchRaw() (string, error) {
+	resp, err := rawClient.Get(f.rawUrl)
+	if err != nil {
+		log.Errorf("Could not fetch file with raw url: %s", f.rawUrl)
+		return "", err
+	}
+	defer resp.Body.Close()
 
-		return content, nil
-	} else {
-		if cachedContent, ok := existing.Get("content").(string); ok {
-			return cachedContent, nil
-		}
-		return "", fmt.Errorf("no cached content available")
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not fetch %q: %s", f.title, resp.Status)
 	}
+
+	contentBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Errorln(err)
+		return "", err
+	}
+
+	return string(contentBytes), nil
+}
+
+func (f file) getContent() (content string, truncated bool, err error) {
+	if f.draft {
+		return f.content, false, nil
+	}
+
+	existing, shouldFetch, err := f.cachedContent()
+	if err != nil {
+		return "", false, err
+	}
+	if existing == nil {
+		return "", false, nil
+	}
+
+	if shouldFetch {
+		return f.fetchContent()
+	}
+	if cachedContent, ok := existing.Get("content").(string); ok {
+		truncated, _ := existing.Get("truncated").(bool)
+		return cachedContent, truncated, nil
+	}
+	return "", false, fmt.Errorf("no cached content available")
 }
 
 type filesDelegate struct {
 	styles FilesBaseStyle
+	syncs  *syncTracker
+	marks  *marks
 }
 
 func (d filesDelegate) Height() int {
-	return 2
+	return 3
 }
 
 func (d filesDelegate) Spacing() int {
@@ -192,35 +407,42 @@ func (d filesDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd {
 		}
 	}
 
+	// loading may have to download the file, the list must not wait for it
 	f, _ := m.SelectedItem().(file)
-	content, err := f.getContent()
-	if err != nil {
-		return func() tea.Msg {
-			return showInfo(err.Error(), info_error)
+	return func() tea.Msg {
+		content, truncated, err := f.getContent()
+		if err != nil {
+			return infoMsg{msg: err.Error(), variant: info_error}
 		}
-	}
 
-	// get the language alias from the title first
-	lexer := lexers.Match(f.title)
-	// if no extension exist, analyze the content itself
-	if lexer == nil {
-		lexer = lexers.Analyse(f.content)
-	}
-	// fallback to whatever the lexer wants (i dont give a shit)
-	if lexer == nil {
-		lexer = lexers.Fallback
-	}
+		var buffer string
+		if f.dirty {
+			if buffer, err = loadBuffer(f.id); err != nil {
+				return infoMsg{msg: err.Error(), variant: info_error}
+			}
+		}
 
-	langName := lexer.Config().Name
-	var alias string
-	if len(lexer.Config().Aliases) > 0 {
-		alias = lexer.Config().Aliases[0]
-	} else {
-		alias = langName
-	}
+		if isBinary([]byte(content)) {
+			return updateEditorContent{content: content, language: "text", fileId: f.id, title: f.title, binary: true}
+		}
 
-	return func() tea.Msg {
-		return updateEditorContent{content: content, language: alias}
+		// the fetched content is analysed, published files only have it once they were loaded
+		lexer := f.lexer(content)
+		// fallback to whatever the lexer wants (i dont give a shit)
+		if lexer == nil {
+			lexer = lexers.Fallback
+		}
+
+		return updateEditorContent{
+			content:      content,
+			language:     lexerAlias(lexer),
+			languageName: lexer.Config().Name,
+			fileId:       f.id,
+			title:        f.title,
+			truncated:    truncated,
+			buffer:       buffer,
+			dirty:        f.dirty,
+		}
 	}
 }
 
@@ -240,11 +462,41 @@ func (d filesDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 		title = d.styles.UnselectedTitle.Render(s.Title())
 	}
 
-	fmt.Fprintln(w, "  "+title)
+	if s.dirty {
+		title += " " + d.styles.Dirty.Render("●")
+	}
+	if d.syncs.isSyncing(s.id) {
+		title += " " + d.syncs.badge()
+	}
+
+	bullet := "  "
+	if d.marks.isFileMarked(s.id) {
+		bullet = "✓ "
+	}
+
+	language := s.languageName()
+	if s.language != "" {
+		language += " *"
+	}
+	summary := slices.DeleteFunc([]string{language, s.linesLabel()}, func(part string) bool { return part == "" })
+	details := []string{s.sizeLabel()}
+	if updatedAt, ok := s.modified(); ok {
+		details = append(details, humanize.Time(updatedAt))
+	}
+
+	subtitle := d.styles.UnselectedSubtitle
+	if index == m.Index() {
+		subtitle = d.styles.SelectedSubtitle
+	}
+	fit := func(text string) string {
+		return subtitle.Render(truncate.Truncate(text, 21, "...", truncate.PositionEnd))
+	}
+
+	fmt.Fprint(w, bullet+title+"\n    "+fit(strings.Join(summary, " · "))+"\n    "+fit(strings.Join(details, " · ")))
 }
 
-func newFileList(items []list.Item, styles FilesBaseStyle) list.Model {
-	l := list.New(items, filesDelegate{styles: styles}, 25, 0)
+func newFileList(items []list.Item, styles FilesBaseStyle, syncs *syncTracker, marks *marks) list.Model {
+	l := list.New(items, filesDelegate{styles: styles, syncs: syncs, marks: marks}, 25, 0)
 	l.Title = "Files"
 	l.SetShowStatusBar(false)
 	l.SetShowHelp(false)
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLineCount(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{content: "", want: 0},
+		{content: "one", want: 1},
+		{content: "one\n", want: 1},
+		{content: "one\ntwo", want: 2},
+		{content: "one\ntwo\n", want: 2},
+		{content: "\n", want: 1},
+		{content: "\n\n", want: 2},
+		{content: "one\r\ntwo\r\n", want: 2},
+	}
+	for _, tt := range tests {
+		if got := lineCount(tt.content); got != tt.want {
+			t.Errorf("lineCount(%q) = %d, want %d", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestFileLabels(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      file
+		wantSize  string
+		wantLines string
+	}{
+		{name: "empty draft", file: file{}, wantSize: "0 B"},
+		{name: "draft", file: file{content: "a\nb\n"}, wantSize: "4 B", wantLines: "2 lines"},
+		{name: "published not loaded", file: file{size: 2048}, wantSize: "2.0 kB"},
+		{name: "published loaded", file: file{size: 1_500_000, lines: 120}, wantSize: "1.5 MB", wantLines: "120 lines"},
+		{name: "binary", file: file{size: 999, binary: true, content: "\x00"}, wantSize: "999 B", wantLines: "binary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.file.sizeLabel(); got != tt.wantSize {
+				t.Errorf("sizeLabel = %q, want %q", got, tt.wantSize)
+			}
+			if got := tt.file.linesLabel(); got != tt.wantLines {
+				t.Errorf("linesLabel = %q, want %q", got, tt.wantLines)
+			}
+		})
+	}
+}
+
+func TestFileModified(t *testing.T) {
+	updated := time.Date(2025, 3, 14, 15, 9, 26, 535000000, time.FixedZone("CET", 3600))
+	tests := []struct {
+		name      string
+		updatedAt string
+		ok        bool
+	}{
+		{name: "stored time", updatedAt: updated.String(), ok: true},
+		{name: "with monotonic clock", updatedAt: updated.String() + " m=+0.012345678", ok: true},
+		{name: "empty", updatedAt: ""},
+		{name: "garbage", updatedAt: "yesterday"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := file{updatedAt: tt.updatedAt}.modified()
+			if ok != tt.ok {
+				t.Fatalf("modified ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && !got.Equal(updated) {
+				t.Errorf("modified = %s, want %s", got, updated)
+			}
+		})
+	}
+}
+
+func TestFileRevision(t *testing.T) {
+	tests := []struct {
+		rawUrl string
+		want   string
+	}{
+		{rawUrl: "https://gist.githubusercontent.com/alice/aa5a315d61ae9438b18d/raw/3c1b5e2f9a/notes.md", want: "3c1b5e2f9a"},
+		{rawUrl: "https://gist.githubusercontent.com/alice/aa5a315d61ae9438b18d/raw", want: ""},
+		{rawUrl: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := (file{rawUrl: tt.rawUrl}).revision(); got != tt.want {
+			t.Errorf("revision(%q) = %q, want %q", tt.rawUrl, got, tt.want)
+		}
+	}
+}
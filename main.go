@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
@@ -11,13 +12,13 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
 	"github.com/google/go-github/v74/github"
 	"github.com/google/uuid"
 	"github.com/ostafen/clover/v2/document"
 	"github.com/ostafen/clover/v2/query"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v3"
-	"golang.design/x/clipboard"
 )
 
 var (
@@ -26,19 +27,24 @@ var (
 
 	storage       = new(store)
 	withVimMotion = false
+
+	// every prompt of the command line reads through the same buffer, a reader per prompt
+	// would swallow the answers meant for the next one when the input is piped
+	stdin = bufio.NewReader(os.Stdin)
 )
 
-func init() {
+func main() {
+	// not done in init, tests set up their own config and database
 	if err := setup(); err != nil {
 		panic(err)
 	}
-	err := clipboard.Init()
-	if err != nil {
-		panic(err)
+	// gisting works without a clipboard, only copying and pasting need one
+	initClipboard()
+	if !canCopy() {
+		DefaultKeymap.Copy.SetEnabled(false)
+		DefaultKeymap.CopyLink.SetEnabled(false)
 	}
-}
 
-func main() {
 	defer storage.db.Close()
 	f, err := initLogger()
 	if err != nil {
@@ -105,9 +111,41 @@ func main() {
 						Value:   "",
 						Usage:   "Set filename for the gist file",
 					},
+					&cli.StringFlag{
+						Name:  "template",
+						Usage: "Add the files of a template stored under the templates folder of the config",
+					},
+					&cli.StringSliceFlag{
+						Name:  "var",
+						Usage: "Set a template variable (name=value), the missing ones are asked for",
+					},
+					&cli.BoolFlag{
+						Name:    "open",
+						Aliases: []string{"o"},
+						Usage:   "Open the created gist in the browser",
+					},
+					&cli.BoolFlag{
+						Name:    "copy",
+						Aliases: []string{"y"},
+						Usage:   "Copy the url of the created gist to the clipboard",
+					},
 				},
 				Action: create,
 			},
+			{
+				Name:      "open",
+				Aliases:   []string{"o"},
+				Usage:     "Open a gist in the browser, or copy one of its links",
+				ArgsUsage: "[GIST_ID] [FILE_NAME]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "copy",
+						Aliases: []string{"y"},
+						Usage:   "Copy a link instead: url, raw (of FILE_NAME), clone or embed",
+					},
+				},
+				Action: openCommand,
+			},
 			{
 				Name:  "delete",
 				Usage: "Delete a gist or file (gisting [GIST_ID] [FILE_NAME])",
@@ -118,7 +156,39 @@ func main() {
 						Value:   "file",
 					},
 				},
-				Action: delete,
+				Action: remove,
+			},
+			{
+				Name:  "auth",
+				Usage: "Manage the Github token used by gisting",
+				Commands: []*cli.Command{
+					{
+						Name:   "status",
+						Usage:  "Show the authenticated user, token scopes and expiry",
+						Action: authStatus,
+					},
+					{
+						Name:   "login",
+						Usage:  "Validate and store a personal token (gisting auth login [TOKEN])",
+						Action: authLogin,
+					},
+					{
+						Name:   "logout",
+						Usage:  "Remove the stored token",
+						Action: authLogout,
+					},
+				},
+			},
+			{
+				Name:  "sync",
+				Usage: "Sync local changes with Github",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "push",
+						Usage: "Upload every drafted gist and every file with local changes",
+					},
+				},
+				Action: syncGists,
 			},
 			{
 				Name:    "drop",
@@ -140,11 +210,80 @@ func main() {
 	}
 }
 
+func printTokenInfo(info *tokenInfo) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "User\t%s\n", info.login)
+	if info.fineGrained {
+		fmt.Fprintln(w, "Type\tfine-grained")
+	} else {
+		fmt.Fprintln(w, "Type\tclassic")
+		fmt.Fprintf(w, "Scopes\t%s\n", strings.Join(info.scopes, ", "))
+	}
+	if info.expiresAt.IsZero() {
+		fmt.Fprintln(w, "Expires\tnever")
+	} else {
+		fmt.Fprintf(w, "Expires\t%s (%s)\n", info.expiresAt.Format(time.DateTime), humanize.Time(info.expiresAt))
+	}
+	w.Flush()
+}
+
+func authStatus(ctx context.Context, c *cli.Command) error {
+	if !cfg.hasAccessToken() {
+		return err_unauthorized
+	}
+	client := newClient(cfg.AccessToken)
+	info, err := validateToken(ctx, client)
+	if info != nil {
+		printTokenInfo(info)
+	}
+	if err != nil {
+		return err
+	}
+	if info.expiresSoon() {
+		fmt.Println("Token expires soon, consider generating a new one")
+	}
+	return nil
+}
+
+func authLogin(ctx context.Context, c *cli.Command) error {
+	token := c.Args().Get(0)
+	if token == "" {
+		fmt.Print("Github Personal Token: ")
+		line, err := readSecret()
+		if err != nil {
+			return err
+		}
+		token = strings.TrimSpace(line)
+	}
+	if token == "" {
+		return errors.New("Token is required")
+	}
+
+	client := newClient(token)
+	info, err := validateToken(ctx, client)
+	if err != nil {
+		return err
+	}
+	if err := cfg.set("AccessToken", token); err != nil {
+		return err
+	}
+	fmt.Printf("Logged in as %q\n", info.login)
+	return nil
+}
+
+func authLogout(ctx context.Context, c *cli.Command) error {
+	if err := cfg.clearSecrets(); err != nil {
+		return err
+	}
+	fmt.Println("Logged out successfully")
+	return nil
+}
+
 func fileList(ctx context.Context, c *cli.Command) error {
 	if !cfg.hasAccessToken() {
 		return err_unauthorized
 	}
-	client := github.NewClient(nil).WithAuthToken(cfg.AccessToken)
+	client := newClient(cfg.AccessToken)
 	out := []string{}
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	gists, _, err := client.Gists.List(ctx, "", &github.GistListOptions{
@@ -193,7 +332,7 @@ func create(ctx context.Context, c *cli.Command) error {
 	if !cfg.hasAccessToken() {
 		return err_unauthorized
 	}
-	client := github.NewClient(nil).WithAuthToken(cfg.AccessToken)
+	client := newClient(cfg.AccessToken)
 	_, _, err := client.Users.Get(context.Background(), "")
 	if err != nil {
 		return err
@@ -214,9 +353,14 @@ func create(ctx context.Context, c *cli.Command) error {
 	fromClipboard := c.Bool("clipboard")
 
 	files := []file{}
+	// binary files cannot go through the json api, they get pushed over git once the gist exists
+	binaries := map[string][]byte{}
 
 	if fromClipboard {
-		content := string(clipboard.Read(clipboard.FmtText))
+		content, err := pasteText()
+		if err != nil {
+			return err
+		}
 		filename := c.String("filename")
 		gist.Files[github.GistFilename(filename)] = github.GistFile{
 			Filename: &filename,
@@ -253,6 +397,11 @@ func create(ctx context.Context, c *cli.Command) error {
 				filename = c.String("filename")
 			}
 
+			if isBinary(data) {
+				binaries[filename] = data
+				continue
+			}
+
 			content := string(data)
 
 			files = append(files, file{})
@@ -264,6 +413,63 @@ func create(ctx context.Context, c *cli.Command) error {
 		}
 	}
 
+	if name := c.String("template"); name != "" {
+		t, err := loadTemplate(name)
+		if err != nil {
+			return err
+		}
+		values, err := parseTemplateVars(c.StringSlice("var"))
+		if err != nil {
+			return err
+		}
+		if err := promptTemplate(stdin, t, values); err != nil {
+			return err
+		}
+		rendered, err := t.render(description, values)
+		if err != nil {
+			return err
+		}
+		for _, f := range rendered {
+			if _, ok := gist.Files[github.GistFilename(f.title)]; ok {
+				return fmt.Errorf("%q is part of the template and given as a file", f.title)
+			}
+			content := f.content
+			if isBinary([]byte(content)) {
+				binaries[f.title] = []byte(content)
+				continue
+			}
+			gist.Files[github.GistFilename(f.title)] = github.GistFile{Filename: github.Ptr(f.title), Content: &content}
+		}
+	}
+
+	// a gist cannot be created without a text file, hold its place until the binaries are pushed
+	placeholder := ""
+	if len(gist.Files) == 0 && len(binaries) > 0 {
+		placeholder = "gisting-placeholder.txt"
+		content := "uploading binary files"
+		gist.Files[github.GistFilename(placeholder)] = github.GistFile{
+			Filename: &placeholder,
+			Content:  &content,
+		}
+	}
+
+	// the id carries the key of the file, since it can differ from its filename
+	scanned := []file{}
+	for key, f := range gist.Files {
+		scanned = append(scanned, file{id: string(key), title: f.GetFilename(), content: f.GetContent()})
+	}
+	if scanned, err = confirmTransforms(scanned); err != nil {
+		return err
+	}
+	scanned, err = confirmSecrets(gist_public, scanned)
+	if err != nil {
+		return err
+	}
+	for _, f := range scanned {
+		content := f.content
+		gist.Files[github.GistFilename(f.id)] = github.GistFile{Filename: github.Ptr(f.title), Content: &content}
+	}
+
 	createdGist, _, err := client.Gists.Create(context.Background(), &gist)
 	if err != nil {
 		var errRes *github.ErrorResponse
@@ -273,34 +479,70 @@ func create(ctx context.Context, c *cli.Command) error {
 		return err
 	}
 
+	if len(binaries) > 0 {
+		change := gitChange{write: binaries, message: "Add binary files"}
+		if placeholder != "" {
+			change.remove = []string{placeholder}
+			delete(gist.Files, github.GistFilename(placeholder))
+		}
+		if err := pushChange(ctx, createdGist.GetID(), change); err != nil {
+			return fmt.Errorf("gist %q created but its binary files could not be pushed: %w", createdGist.GetID(), err)
+		}
+		createdGist, _, err = client.Gists.Get(ctx, createdGist.GetID())
+		if err != nil {
+			return err
+		}
+	}
+
+	contents := map[string]string{}
+	for _, file := range gist.Files {
+		contents[file.GetFilename()] = file.GetContent()
+	}
+	for filename, data := range binaries {
+		contents[filename] = string(data)
+	}
+
 	for _, uploadedFile := range createdGist.Files {
-		for _, file := range gist.Files {
-			if uploadedFile.GetFilename() == file.GetFilename() {
-				doc := document.NewDocument()
-				doc.SetAll(map[string]any{
-					"id":        uuid.New().String(),
-					"gistId":    createdGist.GetID(),
-					"title":     file.GetFilename(),
-					"rawUrl":    uploadedFile.GetRawURL(),
-					"updatedAt": createdGist.GetUpdatedAt().Time.In(time.Local).String(),
-					"content":   file.GetContent(),
-					"draft":     false,
-				})
-				err := storage.db.Insert(string(collectionGistContent), doc)
-				if err != nil {
-					return fmt.Errorf("failed to insert file %q: %w", file.GetFilename(), err)
-				}
-				out += fmt.Sprintf("%q added to the gist %q\n", file.GetFilename(), createdGist.GetDescription())
-			}
+		content, ok := contents[uploadedFile.GetFilename()]
+		if !ok {
+			continue
+		}
+		doc := document.NewDocument()
+		doc.SetAll(map[string]any{
+			"id":        uuid.New().String(),
+			"gistId":    createdGist.GetID(),
+			"title":     uploadedFile.GetFilename(),
+			"rawUrl":    uploadedFile.GetRawURL(),
+			"updatedAt": createdGist.GetUpdatedAt().Time.In(time.Local).String(),
+			"content":   content,
+			"draft":     false,
+		})
+		err := storage.db.Insert(string(collectionGistContent), doc)
+		if err != nil {
+			return fmt.Errorf("failed to insert file %q: %w", uploadedFile.GetFilename(), err)
 		}
+		out += fmt.Sprintf("%q added to the gist %q\n", uploadedFile.GetFilename(), createdGist.GetDescription())
 	}
 
 	fmt.Println(strings.TrimRight(out, "\n"))
+	fmt.Println(createdGist.GetHTMLURL())
+
+	if c.Bool("copy") {
+		if err := copyText(createdGist.GetHTMLURL()); err != nil {
+			return err
+		}
+		fmt.Println("url copied to clipboard")
+	}
+	if c.Bool("open") {
+		if err := openBrowser(createdGist.GetHTMLURL()); err != nil {
+			return fmt.Errorf("could not open the browser: %w", err)
+		}
+	}
 
 	return nil
 }
 
-func delete(ctx context.Context, c *cli.Command) error {
+func remove(ctx context.Context, c *cli.Command) error {
 	if !cfg.hasAccessToken() {
 		return err_unauthorized
 	}
@@ -308,7 +550,7 @@ func delete(ctx context.Context, c *cli.Command) error {
 	gistId := c.Args().Get(0)
 	filename := c.Args().Get(1)
 
-	client := github.NewClient(nil).WithAuthToken(cfg.AccessToken)
+	client := newClient(cfg.AccessToken)
 	_, _, err := client.Users.Get(context.Background(), "")
 	if err != nil {
 		return err
@@ -350,3 +592,76 @@ func delete(ctx context.Context, c *cli.Command) error {
 
 	return nil
 }
+
+func syncGists(ctx context.Context, c *cli.Command) error {
+	if !cfg.hasAccessToken() {
+		return err_unauthorized
+	}
+	if !c.Bool("push") {
+		return errors.New("nothing to sync, pass --push to upload local changes")
+	}
+
+	uploads, err := pendingUploads()
+	if err != nil {
+		return err
+	}
+	if len(uploads) == 0 {
+		fmt.Println("Nothing to upload")
+		return nil
+	}
+
+	var found []scannedFile
+	for i, upload := range uploads {
+		files, err := withPendingContent(upload.files)
+		if err != nil {
+			return err
+		}
+		if files, err = confirmTransforms(files); err != nil {
+			return err
+		}
+		// the pending content is resolved here already, so a redaction is what actually gets pushed
+		uploads[i].files = files
+		uploads[i].resolved = true
+		if !shouldScan(upload.visibility) {
+			continue
+		}
+		scanned, err := scanFiles(nil, files)
+		if err != nil {
+			return err
+		}
+		found = append(found, scanned...)
+	}
+	if len(found) > 0 {
+		redact, err := promptSecrets(stdin, found)
+		if err != nil {
+			return err
+		}
+		for i := 0; redact && i < len(uploads); i++ {
+			uploads[i].files = redactFiles(uploads[i].files, found)
+		}
+	}
+
+	client := newClient(cfg.AccessToken)
+	failed := 0
+	for _, result := range pushPending(ctx, client, uploads) {
+		name := result.upload.name
+		if name == "" {
+			name = result.upload.id
+		}
+		if result.err != nil {
+			failed++
+			var errRes *github.ErrorResponse
+			if errors.As(result.err, &errRes) {
+				result.err = errors.New(errRes.Message)
+			}
+			fmt.Printf("✗ %s: %v\n", name, result.err)
+			continue
+		}
+		fmt.Printf("✓ %s (%d file(s))\n", name, result.sent)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d gist(s) could not be uploaded", failed, len(uploads))
+	}
+	return nil
+}
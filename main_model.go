@@ -3,23 +3,22 @@ package main
 import (
 	"context"
 	"fmt"
-	"maps"
-	"net/http"
+	"image"
 	"slices"
-	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
 	"github.com/google/go-github/v74/github"
 	"github.com/google/uuid"
 	editor "github.com/ionut-t/goeditor/adapter-bubbletea"
 	"github.com/ostafen/clover/v2/document"
 	"github.com/ostafen/clover/v2/query"
-	"golang.design/x/clipboard"
-	"golang.org/x/oauth2"
 )
 
 type pane int
@@ -35,11 +34,45 @@ const (
 type mainModel struct {
 	gists  map[*gist][]list.Item
 	client *github.Client
+	syncs  *syncTracker
+	marks  *marks
 
 	currentPane pane
 	width       int
 	height      int
 	infoMsg     *infoMsg
+	prefetch    prefetchProgress
+	uploads     uploadProgress
+	gistFilter  gistFilter
+
+	// file currently loaded in the editor
+	editorFile      string
+	editorTitle     string
+	editorTruncated bool
+	editorBinary    bool
+	binaryContent   string
+	// binary file decoded as an image, nil for anything else
+	binaryImage image.Image
+	// kitty graphics sequence of the image, sent to the terminal once per loaded file
+	imageData string
+	imageRev  int
+	// content the editor was loaded with or last saved, the buffer is dirty when it differs
+	editorSaved string
+	// buffer last persisted as a local draft
+	editorStashed string
+	// bumped on every edit, only the autosave scheduled by the latest edit goes through
+	autosaveGen int
+	// truncated file the user agreed to edit anyway
+	truncatedAck string
+	preview      markdownPreview
+	// save waiting for the user to confirm it in the review dialog
+	pendingSave *saveReviewMsg
+	// upload or save waiting for the user to decide about possible secrets
+	pendingSecrets *secretsFoundMsg
+	// upload or save waiting for the user to look over the transformed content
+	pendingTransforms *transformsMsg
+	// gist waiting for the variables of its template
+	pendingTemplate *templateChosenMsg
 
 	// tui area
 	gistList list.Model
@@ -58,7 +91,10 @@ func newMainModel(client *github.Client) mainModel {
 	m := mainModel{
 		gists:       map[*gist][]list.Item{},
 		client:      client,
+		syncs:       newSyncTracker(),
+		marks:       newMarks(),
 		keymap:      DefaultKeymap,
+		preview:     markdownPreview{port: viewport.New(0, 0)},
 		help:        help.New(),
 		currentPane: PANE_GISTS,
 		infoMsg:     nil,
@@ -76,20 +112,18 @@ func newMainModel(client *github.Client) mainModel {
 	gistList := []list.Item{}
 
 	// sort gist alphabetically
-	sortedGists := slices.Collect(maps.Keys(m.gists))
-	slices.SortFunc(sortedGists, func(a, b *gist) int {
-		return strings.Compare(a.name, b.name)
-	})
-
-	for _, g := range sortedGists {
+	for _, g := range sortedGists(m.gists) {
 		if firstgist == nil {
 			firstgist = g
 		}
 		gistList = append(gistList, g)
 	}
 
-	m.gistList = newGistList(gistList, m.gistsStyle)
-	m.fileList = newFileList(m.gists[firstgist], m.filesStyle)
+	// the preview only applies to the editor, updateActivePane enables it once the editor is focused
+	m.keymap.Preview.SetEnabled(false)
+
+	m.gistList = newGistList(gistList, m.gistsStyle, m.syncs, m.marks)
+	m.fileList = newFileList(m.gists[firstgist], m.filesStyle, m.syncs, m.marks)
 
 	// dont care about the width and height because we set it inside the tea.WindowSizeMsg
 	textEditor := editor.New(0, 0)
@@ -146,18 +180,53 @@ func (m *mainModel) previous() {
 }
 
 func (m *mainModel) getGists() error {
-	httpClient := &http.Client{Timeout: 5 * time.Second}
-	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
-	gists, _, err := m.client.Gists.List(ctx, "", &github.GistListOptions{
+	// every page is a conditional request, so walking all of them is cheap when nothing changed
+	gists := []*github.Gist{}
+	opts := &github.GistListOptions{
 		ListOptions: github.ListOptions{
-			// TODO: should we handle per page pagination or not?
 			PerPage: 100,
 		},
-	})
+	}
+	for {
+		page, resp, err := m.client.Gists.List(context.Background(), "", opts)
+		if err != nil {
+			return err
+		}
+		gists = append(gists, page...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	publishedGistRawUrls := []string{}
+
+	// the raw url of a file changes with every revision, so unsaved buffers are matched by gist and filename
+	// and carried over to the new document of the file before the old one gets removed as orphaned
+	dirtyBuffers := map[string]string{}
+	dirtyDocs, err := storage.db.FindAll(
+		query.NewQuery(string(collectionGistContent)).Where(query.Field("dirty").Eq(true).And(query.Field("draft").Eq(false))),
+	)
 	if err != nil {
 		return err
 	}
-	publishedGistRawUrls := []string{}
+	for _, doc := range dirtyDocs {
+		buffer, _ := doc.Get("buffer").(string)
+		dirtyBuffers[doc.Get("gistId").(string)+"/"+doc.Get("title").(string)] = buffer
+	}
+
+	// language overrides are carried over the same way
+	languages := map[string]string{}
+	languageDocs, err := storage.db.FindAll(
+		query.NewQuery(string(collectionGistContent)).Where(query.Field("language").Exists().And(query.Field("draft").Eq(false))),
+	)
+	if err != nil {
+		return err
+	}
+	for _, doc := range languageDocs {
+		if language, ok := doc.Get("language").(string); ok && language != "" {
+			languages[doc.Get("gistId").(string)+"/"+doc.Get("title").(string)] = language
+		}
+	}
 
 	// get the uploaded gists
 	for _, g := range gists {
@@ -193,6 +262,15 @@ func (m *mainModel) getGists() error {
 					"updatedAt": i.updatedAt,
 					"draft":     i.draft,
 				})
+				if buffer, ok := dirtyBuffers[i.gistId+"/"+i.title]; ok {
+					doc.Set("dirty", true)
+					doc.Set("buffer", buffer)
+					i.dirty = true
+				}
+				if language, ok := languages[i.gistId+"/"+i.title]; ok {
+					doc.Set("language", language)
+					i.language = language
+				}
 				err := storage.db.Save(string(collectionGistContent), doc)
 				if err != nil {
 					return fmt.Errorf(`failed to insert gist "%s": %w`, g.GetDescription(), err)
@@ -212,7 +290,11 @@ func (m *mainModel) getGists() error {
 				if c, ok := existing.Get("content").(string); ok {
 					i.content = c
 				}
+				i.dirty, _ = existing.Get("dirty").(bool)
+				i.language, _ = existing.Get("language").(string)
+				i.truncated, _ = existing.Get("truncated").(bool)
 			}
+			i.size = f.GetSize()
 
 			items = append(items, i)
 		}
@@ -222,12 +304,25 @@ func (m *mainModel) getGists() error {
 			visibility = gist_public
 		}
 
+		// the listing stops at the file cap, ask the gist itself whether some files are missing
+		var truncated bool
+		if len(g.GetFiles()) >= gistFileCap {
+			detail, err := getGistDetail(context.Background(), m.client, g.GetID())
+			if err != nil {
+				log.Errorf("could not check if gist %q is truncated\n%v", g.GetID(), err)
+			} else {
+				truncated = detail.Truncated
+			}
+		}
+
 		g := gist{
 			name:      g.GetDescription(),
 			id:        g.GetID(),
 			status:    gist_status_published,
 			updatedAt: g.GetUpdatedAt().Time.In(time.Local),
 			visiblity: visibility,
+			truncated: truncated,
+			htmlUrl:   g.GetHTMLURL(),
 		}
 		m.gists[&g] = items
 	}
@@ -281,12 +376,13 @@ func (m *mainModel) getGists() error {
 				gistId:    doc.Get("gistId").(string),
 				title:     doc.Get("title").(string),
 				rawUrl:    doc.Get("rawUrl").(string),
-				stale:     doc.Get("stale").(bool),
 				desc:      doc.Get("desc").(string),
 				updatedAt: doc.Get("updatedAt").(string),
 				content:   doc.Get("content").(string),
 				draft:     doc.Get("draft").(bool),
 			}
+			i.dirty, _ = doc.Get("dirty").(bool)
+			i.language, _ = doc.Get("language").(string)
 			items = append(items, i)
 		}
 		m.gists[&g] = items
@@ -294,6 +390,14 @@ func (m *mainModel) getGists() error {
 	return nil
 }
 
+type fileSavedMsg struct {
+	gist     *gist
+	file     file
+	content  string
+	response *github.Gist
+	err      error
+}
+
 func (m *mainModel) saveFileContent(content string) []tea.Cmd {
 	var cmds []tea.Cmd
 	selectedGist := m.gistList.SelectedItem()
@@ -312,29 +416,78 @@ func (m *mainModel) saveFileContent(content string) []tea.Cmd {
 
 	f, _ := selectedFile.(file)
 
+	return m.transformSave(uploadCheck{action: scan_save, gist: g, file: f, content: content})
+}
+
+// saveChecked saves the content, after a review when it is asked for
+func (m *mainModel) saveChecked(g *gist, f file, content string) []tea.Cmd {
+	if cfg.ReviewBeforeSave && !f.draft {
+		return m.reviewSave(g, f, content)
+	}
+	return m.saveFile(g, f, content)
+}
+
+func (m *mainModel) saveFile(g *gist, f file, content string) []tea.Cmd {
+	var cmds []tea.Cmd
+	if f.draft {
+		return []tea.Cmd{func() tea.Msg {
+			return fileSavedMsg{gist: g, file: f, content: content}
+		}}
+	}
+
+	ctx, tick, ok := m.syncs.begin(f.id)
+	if !ok {
+		return []tea.Cmd{showInfo("file is still syncing", info_default)}
+	}
+
+	gist := github.Gist{
+		Files: map[github.GistFilename]github.GistFile{
+			github.GistFilename(f.title): {
+				Content: &content,
+			},
+		},
+	}
+	client := m.client
+	gistId := g.id
+	cmds = append(cmds, tick, func() tea.Msg {
+		if cfg.GitBackend {
+			updatedGist, err := pushGistChange(ctx, client, gistId, gitChange{
+				write:   map[string][]byte{f.title: []byte(content)},
+				message: fmt.Sprintf("Update %s", f.title),
+			})
+			return fileSavedMsg{gist: g, file: f, content: content, response: updatedGist, err: err}
+		}
+		updatedGist, _, err := client.Gists.Edit(ctx, gistId, &gist)
+		return fileSavedMsg{gist: g, file: f, content: content, response: updatedGist, err: err}
+	})
+	return cmds
+}
+
+func (m *mainModel) fileSaved(msg fileSavedMsg) []tea.Cmd {
+	var cmds []tea.Cmd
+	g, f, content := msg.gist, msg.file, msg.content
+
+	if !f.draft {
+		m.syncs.done(f.id)
+	}
+	if msg.err != nil {
+		return syncFailed("could not update gist from github", msg.err)
+	}
+
 	updates := map[string]interface{}{
 		"id":        f.id,
 		"content":   content,
 		"updatedAt": f.updatedAt,
+		"dirty":     false,
+		"buffer":    "",
+		// what was saved is the whole file on github now
+		"truncated": false,
 	}
 
 	var updateTime time.Time
 
 	if !f.draft {
-		gist := github.Gist{
-			Files: map[github.GistFilename]github.GistFile{
-				github.GistFilename(f.title): {
-					Content: &content,
-				},
-			},
-		}
-		updatedGist, _, err := m.client.Gists.Edit(context.Background(), g.id, &gist)
-		if err != nil {
-			log.Errorf("could not update gist file %q from Github\n%w", f.title, err)
-			cmds = append(cmds, showInfo("could not update gist from github", info_error))
-			return cmds
-		}
-
+		updatedGist := msg.response
 		// update the rawUrl because it changes every update (learned it the hard way)
 		for _, file := range updatedGist.GetFiles() {
 			if file.GetFilename() == f.title {
@@ -353,7 +506,7 @@ func (m *mainModel) saveFileContent(content string) []tea.Cmd {
 
 	q := query.NewQuery(string(collectionGistContent)).Where(query.Field("id").Eq(f.id))
 	if err := storage.db.Update(q, updates); err != nil {
-		log.Errorf("could not gist content on db %q\n%w", f.title, err)
+		log.Errorf("could not gist content on db %q\n%v", f.title, err)
 		cmds = append(cmds, showInfo("could not gist content on db", info_error))
 		return cmds
 	}
@@ -367,38 +520,220 @@ func (m *mainModel) saveFileContent(content string) []tea.Cmd {
 		rawUrl:    updates["rawUrl"].(string),
 		content:   content,
 		updatedAt: updates["updatedAt"].(string),
-		stale:     false,
 		draft:     f.draft,
+		size:      len(content),
+		language:  f.language,
+		detected:  f.detected,
+		lines:     lineCount(content),
 	}
 
-	idx := m.fileList.Index()
 	g.updatedAt = updateTime
-	m.gists[g][idx] = updatedFile
+	if m.editorFile == f.id {
+		m.editorSaved = content
+	}
 
-	cmds = append(cmds, m.fileList.SetItem(idx, updatedFile))
+	cmds = append(cmds, m.replaceFile(g, updatedFile)...)
 	cmds = append(cmds, showInfo("gist content saved", info_default))
 
 	return cmds
 }
 
+func (m *mainModel) selectedGist() *gist {
+	g, _ := m.gistList.SelectedItem().(*gist)
+	return g
+}
+
+func (m *mainModel) gistIndex(g *gist) int {
+	for idx, item := range m.gistList.Items() {
+		if item == g {
+			return idx
+		}
+	}
+	return -1
+}
+
+func fileIndex(items []list.Item, id string) int {
+	for idx, item := range items {
+		if f, ok := item.(file); ok && f.id == id {
+			return idx
+		}
+	}
+	return -1
+}
+
+// refreshFileList shows the latest files of the gist, as long as it is still the selected one
+func (m *mainModel) refreshFileList(g *gist) []tea.Cmd {
+	if m.selectedGist() != g {
+		return nil
+	}
+	cmd := m.fileList.SetItems(m.gists[g])
+	_, updateFileList := m.fileList.Update(nil)
+	return []tea.Cmd{cmd, updateFileList}
+}
+
+// replaceFile swaps the file with the same id inside the gist for the updated one
+func (m *mainModel) replaceFile(g *gist, f file) []tea.Cmd {
+	files := m.gists[g]
+	if idx := fileIndex(files, f.id); idx >= 0 {
+		files[idx] = f
+	}
+	m.gists[g] = files
+
+	if m.selectedGist() != g {
+		return nil
+	}
+	if idx := fileIndex(m.fileList.Items(), f.id); idx >= 0 {
+		return []tea.Cmd{m.fileList.SetItem(idx, f)}
+	}
+	return nil
+}
+
+func (m *mainModel) removeFile(g *gist, id string) []tea.Cmd {
+	var cmds []tea.Cmd
+	files := m.gists[g]
+	if idx := fileIndex(files, id); idx >= 0 {
+		m.gists[g] = slices.Delete(slices.Clone(files), idx, idx+1)
+	}
+
+	if m.selectedGist() != g {
+		return cmds
+	}
+
+	idx := fileIndex(m.fileList.Items(), id)
+	if idx < 0 {
+		return cmds
+	}
+	wasSelected := idx == m.fileList.Index()
+	m.fileList.RemoveItem(idx)
+
+	// shift focus back to the previous deleted file item
+	if wasSelected && len(m.fileList.Items()) > 0 {
+		if idx > 0 {
+			idx--
+		}
+		m.fileList.Select(idx)
+	}
+
+	_, updatedFileList := m.fileList.Update(nil)
+	cmds = append(cmds, updatedFileList)
+	return cmds
+}
+
+func (m *mainModel) removeGist(g *gist) []tea.Cmd {
+	var cmds []tea.Cmd
+	selected := m.selectedGist()
+	delete(m.gists, g)
+
+	idx := m.gistIndex(g)
+	if idx < 0 {
+		return cmds
+	}
+	m.gistList.RemoveItem(idx)
+
+	if len(m.gistList.Items()) == 0 {
+		cmds = append(cmds, m.fileList.SetItems([]list.Item{}))
+		_, updateList := m.fileList.Update(nil)
+		return append(cmds, updateList)
+	}
+
+	if selected == g {
+		// shift focus back to the previous deleted gist item
+		if idx > 0 {
+			idx--
+		}
+		m.gistList.Select(idx)
+	} else if selectedIdx := m.gistIndex(selected); selectedIdx >= 0 {
+		m.gistList.Select(selectedIdx)
+	}
+
+	cmds = append(cmds, m.fileList.SetItems(m.gists[m.selectedGist()]))
+	_, updateList := m.fileList.Update(nil)
+	cmds = append(cmds, updateList)
+	return cmds
+}
+
 func (m *mainModel) copyToClipboard() tea.Cmd {
 	selectedItem := m.fileList.SelectedItem()
 	f, ok := selectedItem.(file)
 	if !ok {
 		return showInfo("no file selected", info_default)
 	}
-	clipboard.Write(clipboard.FmtText, []byte(f.content))
-	return showInfo("content copied to clipboard", info_default)
+	if isBinary([]byte(f.content)) {
+		return showInfo("binary content cannot be copied", info_default)
+	}
+	return copyCmd(f.content, "content copied to clipboard")
 }
 
 type updateEditorContent struct {
 	content  string
 	language string
+	// name of the language, shown in the file list
+	languageName string
+	fileId       string
+	// only part of the file could be loaded
+	truncated bool
+	// binary files get a preview instead of the editor
+	binary bool
+	title  string
+	// unsaved buffer left behind the last time the file was edited
+	buffer string
+	dirty  bool
+}
+
+// confirmTruncatedMsg asks the user to confirm editing a file that was only partially loaded
+type confirmTruncatedMsg struct{}
+
+// guardEditor keeps the user out of the editor when the selected file cannot be edited safely,
+// it returns nil when the editor can be entered
+func (m *mainModel) guardEditor() tea.Cmd {
+	if m.editorBinary {
+		return showInfo("binary files cannot be edited", info_default)
+	}
+	if m.editorTruncated && m.truncatedAck != m.editorFile {
+		return func() tea.Msg { return confirmTruncatedMsg{} }
+	}
+	return nil
+}
+
+func (m *mainModel) showPreview() bool {
+	return m.preview.enabled && isMarkdown(m.editorTitle) && !m.editorBinary
+}
+
+// editorArea is the width left for the editor and the preview next to the gist and file lists
+func (m *mainModel) editorArea() int {
+	return m.width - lipgloss.Width(m.gistList.View()) - lipgloss.Width(m.fileList.View())
+}
+
+// resizeEditor gives the editor the whole area, or half of it while the preview is shown
+func (m *mainModel) resizeEditor() {
+	if m.showPreview() {
+		m.editor.SetSize(m.editorArea()/2, m.height+2)
+		return
+	}
+	gv, _ := m.gistsStyle.Base.GetFrameSize()
+	fv, _ := m.filesStyle.Base.GetFrameSize()
+	m.editor.SetSize(m.width-fv-gv, m.height+2)
+}
+
+// syncPreview re-renders the preview from the editor content and scrolls it along with the editor cursor
+func (m *mainModel) syncPreview() {
+	if !m.showPreview() {
+		return
+	}
+	area := m.editorArea()
+	m.preview.render(m.editor.GetCurrentContent(), area-area/2-2, m.height+2)
+	row, _ := m.editor.GetCursorPosition()
+	m.preview.follow(row)
 }
 
 func (m mainModel) Init() tea.Cmd {
 	_, initFileList := m.fileList.Update(nil)
-	return tea.Batch(initFileList, m.editor.CursorBlink())
+	cmds := []tea.Cmd{initFileList, m.editor.CursorBlink(), m.startPrefetch()}
+	// buffers left unsaved when gisting last exited, be it on purpose or not, are loaded in place of the content
+	if n := m.dirtyFiles(); n > 0 {
+		cmds = append(cmds, showInfo(fmt.Sprintf("restored unsaved changes of %d file(s)", n), info_default))
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m *mainModel) resetListHeight() {
@@ -427,12 +762,62 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Batch(cmds...)
 		}
 
+	case autosaveMsg:
+		cmds = append(cmds, m.autosave(msg)...)
+
 	case updateEditorContent:
+		// what got loaded fills in the details of the file list
+		if g, f, ok := m.findFile(msg.fileId); ok {
+			loaded := f
+			loaded.binary = msg.binary
+			loaded.truncated = msg.truncated
+			if msg.languageName != "" {
+				loaded.detected = msg.languageName
+			}
+			if !msg.binary {
+				loaded.lines = lineCount(msg.content)
+			}
+			if loaded != f {
+				cmds = append(cmds, m.replaceFile(g, loaded)...)
+			}
+		}
+		// files load in the background, one that is no longer selected must not take the editor
+		if selected, _ := m.fileList.SelectedItem().(file); selected.id != msg.fileId {
+			break
+		}
+		cmds = append(cmds, m.stashEditor()...)
+		// the selected file gets loaded again on every move through the list, the terminal keeps its image
+		sameImage := msg.binary && m.editorBinary && m.editorFile == msg.fileId && m.binaryContent == msg.content
+		m.editorFile = msg.fileId
+		m.editorSaved = msg.content
+		m.editorStashed = msg.buffer
+		m.editorTruncated = msg.truncated
+		m.editorBinary = msg.binary
+		m.editorTitle = msg.title
+		if !sameImage {
+			m.binaryContent, m.binaryImage, m.imageData = "", nil, ""
+		}
+		if msg.binary && !sameImage {
+			m.binaryContent = msg.content
+			m.binaryImage = decodeImage(msg.content)
+			if m.binaryImage != nil && supportsKittyGraphics() {
+				m.imageData = transmitKitty(m.binaryImage)
+			}
+			m.imageRev++
+		}
+		if msg.binary {
+			msg.content = ""
+		}
+		if msg.dirty {
+			msg.content = msg.buffer
+		}
 		m.editor.SetContent(string(msg.content))
 		m.editor.SetLanguage(msg.language, cfg.Theme)
 		editorModel, cmd := m.editor.Update(msg)
 		cmds = append(cmds, cmd)
 		m.editor = editorModel.(editor.Model)
+		m.resizeEditor()
+		m.syncPreview()
 		cmds = append(cmds, m.updateActivePane(msg)...)
 
 	case tea.KeyMsg:
@@ -441,6 +826,11 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.help.ShowAll = !m.help.ShowAll
 			m.resetListHeight()
 		case "ctrl+h":
+			if m.currentPane == PANE_GISTS {
+				if cmd := m.guardEditor(); cmd != nil {
+					return m, cmd
+				}
+			}
 			m.previous()
 			return m, tea.Batch(m.updateActivePane(msg)...)
 		case "ctrl+l", "tab":
@@ -451,6 +841,11 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.editor = editorModel.(editor.Model)
 				return m, tea.Batch(cmds...)
 			}
+			if m.currentPane == PANE_FILES {
+				if cmd := m.guardEditor(); cmd != nil {
+					return m, cmd
+				}
+			}
 			m.next()
 			// hack: send keypress cmd to trigger cursor blink
 			if m.currentPane == PANE_EDITOR {
@@ -468,9 +863,19 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch m.currentPane {
 		case PANE_GISTS:
 			switch msg.String() {
+			case " ":
+				if g := m.selectedGist(); g != nil {
+					m.marks.toggleGist(g)
+				}
+			case "esc":
+				m.marks.clear()
+			case "f":
+				return m, tea.Batch(m.toggleGistFilter()...)
 			case "up", "down", "j", "k":
 				m.gistList, cmd = m.gistList.Update(msg)
 				cmds = append(cmds, cmd)
+				// files can only be marked within a single gist
+				m.marks.clearFiles()
 				if selectedGist, ok := m.gistList.SelectedItem().(*gist); ok {
 					for gist, _ := range m.gists {
 						if gist.id == selectedGist.id {
@@ -491,12 +896,19 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case PANE_FILES:
 			switch msg.String() {
+			case " ":
+				if f, ok := m.fileList.SelectedItem().(file); ok {
+					m.marks.toggleFile(f.id)
+				}
+			case "esc":
+				m.marks.clear()
 			case "up", "down", "j", "k":
 				m.fileList, cmd = m.fileList.Update(msg)
 				cmds = append(cmds, cmd)
 
 			case "y":
-				if m.currentPane != PANE_EDITOR {
+				// the binding is turned off when nothing can take the copy
+				if m.currentPane != PANE_EDITOR && m.keymap.Copy.Enabled() {
 					cmds = append(cmds, m.copyToClipboard())
 					cmds = append(cmds, m.updateActivePane(msg)...)
 					return m, tea.Batch(cmds...)
@@ -504,6 +916,9 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// same thing here, trigger cursor blink on editor on select
 			case "enter":
+				if cmd := m.guardEditor(); cmd != nil {
+					return m, cmd
+				}
 				m.next()
 				return m, func() tea.Msg {
 					return tea.KeyMsg{
@@ -515,6 +930,16 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case PANE_EDITOR:
+			if key.Matches(msg, m.keymap.Preview) {
+				if !isMarkdown(m.editorTitle) {
+					return m, showInfo("preview is only available for markdown files", info_default)
+				}
+				m.preview.enabled = !m.preview.enabled
+				m.resizeEditor()
+				m.syncPreview()
+				return m, nil
+			}
+
 			switch msg.String() {
 			case "ctrl+s":
 				m.editor.Blur()
@@ -530,6 +955,8 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			editorModel, cmd := m.editor.Update(msg)
 			cmds = append(cmds, cmd)
 			m.editor = editorModel.(editor.Model)
+			m.syncPreview()
+			cmds = append(cmds, m.scheduleAutosave())
 			cmds = append(cmds, m.updateActivePane(msg)...)
 		}
 
@@ -537,15 +964,13 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height - 2
 
-		gv, _ := m.gistsStyle.Base.GetFrameSize()
 		m.gistList.SetSize(45, m.height)
-
-		fv, _ := m.filesStyle.Base.GetFrameSize()
 		m.fileList.SetSize(20, m.height)
 
 		m.resetListHeight()
 
-		m.editor.SetSize(m.width-fv-gv, m.height+2)
+		m.resizeEditor()
+		m.syncPreview()
 	default:
 	}
 
@@ -560,6 +985,12 @@ func (m *mainModel) updateActivePane(msg tea.Msg) []tea.Cmd {
 
 	m.help.ShowAll = false
 	m.resetListHeight()
+	m.keymap.Preview.SetEnabled(m.currentPane == PANE_EDITOR)
+
+	// keep whatever was left unsaved in the editor once it loses focus
+	if m.currentPane != PANE_EDITOR {
+		cmds = append(cmds, m.stashEditor()...)
+	}
 
 	switch m.currentPane {
 	case PANE_GISTS:
@@ -619,18 +1050,53 @@ func (m mainModel) View() string {
 		infoView = m.styles.InfoLabel.Render(str)
 	}
 
-	return lipgloss.JoinHorizontal(
-		lipgloss.Top,
-		lipgloss.JoinVertical(
+	// show how much of the api quota is left next to the info message
+	if remaining, limit, reset, known := rateLimit.get(); known {
+		quota := fmt.Sprintf("\uf0e7 %d/%d", remaining, limit)
+		if remaining < limit/10 {
+			quota += fmt.Sprintf(" (resets %s)", humanize.Time(reset))
+		}
+		infoView = lipgloss.JoinHorizontal(lipgloss.Top, m.styles.InfoLabel.Render(quota+"  "), infoView)
+	}
+
+	if m.uploads.total > 0 {
+		progress := fmt.Sprintf("\uf093 %d/%d  ", m.uploads.done, m.uploads.total)
+		infoView = lipgloss.JoinHorizontal(lipgloss.Top, m.styles.InfoLabel.Render(progress), infoView)
+	}
+
+	if m.prefetch.total > 0 {
+		progress := fmt.Sprintf("\uf019 %d/%d  ", m.prefetch.done, m.prefetch.total)
+		infoView = lipgloss.JoinHorizontal(lipgloss.Top, m.styles.InfoLabel.Render(progress), infoView)
+	}
+
+	sidebar := lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.JoinHorizontal(
 			lipgloss.Left,
-			lipgloss.JoinHorizontal(
-				lipgloss.Left,
-				m.gistList.View(),
-				m.fileList.View(),
-			),
-			infoView,
-			lipgloss.NewStyle().Render(m.help.View(m.keymap)),
+			m.gistList.View(),
+			m.fileList.View(),
 		),
-		m.editor.View(),
+		infoView,
+		lipgloss.NewStyle().Render(m.help.View(m.keymap)),
+	)
+
+	editorView := m.editor.View()
+	if m.showPreview() {
+		editorView = lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			editorView,
+			lipgloss.NewStyle().PaddingLeft(2).Render(m.preview.port.View()),
+		)
+	}
+	if m.editorBinary {
+		editorView = lipgloss.NewStyle().PaddingLeft(2).Render(
+			renderBinaryPreview(m.editorTitle, m.binaryContent, m.binaryImage, m.imageData != "", m.width-lipgloss.Width(sidebar)-2, m.height),
+		)
+	}
+
+	return lipgloss.JoinHorizontal(
+		lipgloss.Top,
+		sidebar,
+		editorView,
 	)
 }
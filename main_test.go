@@ -0,0 +1,25 @@
+package main
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+// TestMain keeps the config and database of the tests in a temporary folder
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "gisting-test")
+	if err != nil {
+		panic(err)
+	}
+	cfg = &config{ConfigPath: dir, Theme: "nord"}
+	if err := storage.init(dir); err != nil {
+		panic(err)
+	}
+	log.SetOutput(io.Discard)
+
+	code := m.Run()
+	storage.db.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// marks holds the gists and files marked for a bulk action, files can only be marked within the selected gist
+type marks struct {
+	gists map[*gist]bool
+	files map[string]bool
+}
+
+func newMarks() *marks {
+	return &marks{
+		gists: map[*gist]bool{},
+		files: map[string]bool{},
+	}
+}
+
+func (s *marks) toggleGist(g *gist) {
+	if s.gists[g] {
+		delete(s.gists, g)
+		return
+	}
+	s.gists[g] = true
+}
+
+func (s *marks) toggleFile(id string) {
+	if s.files[id] {
+		delete(s.files, id)
+		return
+	}
+	s.files[id] = true
+}
+
+func (s *marks) isGistMarked(g *gist) bool   { return s.gists[g] }
+func (s *marks) isFileMarked(id string) bool { return s.files[id] }
+
+func (s *marks) clear() {
+	clear(s.gists)
+	clear(s.files)
+}
+
+func (s *marks) clearFiles() {
+	clear(s.files)
+}
+
+// markedGists returns the marked gists sorted by name
+func (s *marks) markedGists() []*gist {
+	gists := []*gist{}
+	for g := range s.gists {
+		gists = append(gists, g)
+	}
+	slices.SortFunc(gists, func(a, b *gist) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return gists
+}
+
+// markedFiles returns the marked files out of the given items, keeping their order
+func (s *marks) markedFiles(items []list.Item) []file {
+	files := []file{}
+	for _, item := range items {
+		if f, ok := item.(file); ok && s.files[f.id] {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// guardTransfer keeps the move and copy dialogs closed when there is nothing to move, it returns nil otherwise
+func (m *mainModel) guardTransfer() tea.Cmd {
+	if m.currentPane != PANE_FILES {
+		return showInfo("files can only be moved from the files pane", info_default)
+	}
+	if len(m.transferSelection()) == 0 {
+		return showInfo("there are no files to move or copy", info_default)
+	}
+	if len(m.gists) < 2 {
+		return showInfo("there is no other gist to move files to", info_default)
+	}
+	return nil
+}
+
+// transferSelection returns the marked files of the selected gist, or the selected file when none are marked
+func (m *mainModel) transferSelection() []file {
+	if len(m.marks.files) > 0 {
+		return m.marks.markedFiles(m.gists[m.selectedGist()])
+	}
+	if f, ok := m.fileList.SelectedItem().(file); ok {
+		return []file{f}
+	}
+	return nil
+}
+
+func (m *mainModel) gistById(id string) *gist {
+	for g := range m.gists {
+		if g.id == id {
+			return g
+		}
+	}
+	return nil
+}
+
+// targetOptions lists every gist files can be moved to from the source gist
+func (m *mainModel) targetOptions(source *gist) []huh.Option[string] {
+	options := []huh.Option[string]{}
+	for _, g := range sortedGists(m.gists) {
+		if g == source {
+			continue
+		}
+		label := g.name
+		if g.status == gist_status_drafted {
+			label += " (Draft)"
+		}
+		options = append(options, huh.NewOption(label, g.id))
+	}
+	return options
+}
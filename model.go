@@ -1,8 +1,9 @@
 package main
 
 import (
-	"context"
+	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/list"
@@ -56,6 +57,12 @@ type model struct {
 	width   int
 	height  int
 	infoMsg *infoMsg
+
+	// escape sequences that go out with the next frames, see queueEscape
+	escapes    string
+	escapesGen int
+	// revision of the kitty image the terminal holds, 0 when it holds none
+	imageShown int
 }
 
 func initialModel() model {
@@ -71,8 +78,8 @@ func initialModel() model {
 	}
 }
 
-// create gist and store it in drafted gist collection
-func (m *model) createGist(name string, visibility gistVisibility) []tea.Cmd {
+// create gist and store it in drafted gist collection, the gist is nil when it could not be stored
+func (m *model) createGist(name string, visibility gistVisibility) (*gist, []tea.Cmd) {
 	var cmds []tea.Cmd
 
 	if name == "" {
@@ -89,11 +96,14 @@ func (m *model) createGist(name string, visibility gistVisibility) []tea.Cmd {
 	})
 
 	if err := storage.db.Insert(string(collectionDraftedGists), doc); err != nil {
-		return cmds
+		log.Errorf("could not insert drafted gist %q\n%v", name, err)
+		return nil, append(cmds, showInfo("could not insert into db", info_error))
 	}
 
-	// get the current items from gistList
-	gistItems := m.mainScreen.gistList.Items()
+	// the new draft would be hidden by the gist filter otherwise
+	m.mainScreen.gistFilter = gist_filter_all
+	m.mainScreen.gistList.Title = gistListTitle(gist_filter_all)
+	gistItems := m.mainScreen.filteredGists()
 
 	emptyList := []list.Item{}
 	g := gist{
@@ -130,65 +140,66 @@ func (m *model) createGist(name string, visibility gistVisibility) []tea.Cmd {
 	}
 
 	cmds = append(cmds, gistCmd, fileCmd, updateFileList)
-	cmds = append(cmds, showInfo("new gist created", info_default))
 
-	return cmds
+	return &g, cmds
+}
+
+type gistDeletedMsg struct {
+	gist *gist
+	err  error
 }
 
 func (m *model) deleteGist(g *gist) []tea.Cmd {
-	var cmds []tea.Cmd
-	if g.status == gist_status_published {
-		_, err := m.client.Gists.Delete(context.Background(), g.id)
-		if err != nil {
-			log.Errorf("could not delete gist:\n%w", err)
-			cmds = append(cmds, showInfo("could not delete gist", info_error))
-			return cmds
-		}
-	} else {
-		err := storage.db.Delete(query.NewQuery(string(collectionDraftedGists)).Where(query.Field("id").Eq(g.id)))
-		if err != nil {
-			log.Errorf("could not delete draft gist:\n%w", err)
-			cmds = append(cmds, showInfo("could not delete draft gist", info_error))
-			return cmds
-		}
+	ctx, tick, ok := m.mainScreen.syncs.begin(g.id)
+	if !ok {
+		return []tea.Cmd{showInfo("gist is still syncing", info_default)}
 	}
 
-	idx := m.mainScreen.gistList.Index()
-	m.mainScreen.gistList.RemoveItem(idx)
-
-	if len(m.mainScreen.gistList.Items()) > 0 {
-		// shift focus back to the previous deleted gist item
-		if idx > 0 {
-			idx--
+	id, status := g.id, g.status
+	client := m.client
+	return []tea.Cmd{tick, func() tea.Msg {
+		if status == gist_status_published {
+			_, err := client.Gists.Delete(ctx, id)
+			return gistDeletedMsg{gist: g, err: err}
 		}
-		m.mainScreen.gistList.Select(idx)
-		gItem := m.mainScreen.gistList.SelectedItem()
-		selectedGist, _ := gItem.(*gist)
-		cmd := m.mainScreen.fileList.SetItems(m.mainScreen.gists[selectedGist])
-		cmds = append(cmds, cmd)
-		_, updateList := m.mainScreen.fileList.Update(nil)
-		cmds = append(cmds, updateList)
+		err := storage.db.Delete(query.NewQuery(string(collectionDraftedGists)).Where(query.Field("id").Eq(id)))
+		return gistDeletedMsg{gist: g, err: err}
+	}}
+}
+
+func (m *model) gistDeleted(msg gistDeletedMsg) []tea.Cmd {
+	m.mainScreen.syncs.done(msg.gist.id)
+	if msg.err != nil {
+		return syncFailed("could not delete gist", msg.err)
 	}
 
+	cmds := m.mainScreen.removeGist(msg.gist)
 	cmds = append(cmds, showInfo("gist deleted", info_default))
-
 	return cmds
 }
 
-// create gist and store it in drafted file collection
-func (m *model) createFile(title string, gist *gist) []tea.Cmd {
+type fileCreatedMsg struct {
+	gist     *gist
+	file     file
+	response *github.Gist
+	err      error
+}
+
+// create gist and store it in drafted file collection, the language picks its extension and boilerplate
+func (m *model) createFile(title, language string, gist *gist) []tea.Cmd {
 	var cmds []tea.Cmd
 
+	title = suggestFilename(title, language)
 	id := uuid.New().String()
 	f := file{
 		id:     id,
 		gistId: gist.id,
 		title:  title,
 		// had to add something to string or else github will complain that we're deleting a missing file from the current gist
-		content:   "New File",
+		content:   boilerplate(language),
 		desc:      "",
 		rawUrl:    "",
-		stale:     false,
+		dirty:     false,
 		updatedAt: time.Now().In(time.Local).String(),
 		draft:     true,
 	}
@@ -206,22 +217,55 @@ func (m *model) createFile(title string, gist *gist) []tea.Cmd {
 		}
 	}
 
-	if gist.status == gist_status_published {
-		g := github.Gist{
-			Description: &gist.name,
-			Files:       map[github.GistFilename]github.GistFile{},
-		}
+	if gist.status != gist_status_published {
+		return []tea.Cmd{func() tea.Msg {
+			return fileCreatedMsg{gist: gist, file: f}
+		}}
+	}
 
-		// add the new created file to the map
-		g.Files[github.GistFilename(f.title)] = github.GistFile{Filename: &f.title, Content: &f.content}
+	ctx, tick, ok := m.mainScreen.syncs.begin(gist.id)
+	if !ok {
+		return []tea.Cmd{showInfo("gist is still syncing", info_default)}
+	}
 
-		response, _, err := m.client.Gists.Edit(context.Background(), gist.id, &g)
-		if err != nil {
-			log.Errorf("could not create gist file\n %v", err)
-			cmds = append(cmds, showInfo("could not create gist file", info_error))
-			return cmds
+	name := gist.name
+	g := github.Gist{
+		Description: &name,
+		Files:       map[github.GistFilename]github.GistFile{},
+	}
+
+	// add the new created file to the map
+	g.Files[github.GistFilename(f.title)] = github.GistFile{Filename: &f.title, Content: &f.content}
+
+	client := m.client
+	gistId := gist.id
+	cmds = append(cmds, tick, func() tea.Msg {
+		if cfg.GitBackend {
+			response, err := pushGistChange(ctx, client, gistId, gitChange{
+				write:   map[string][]byte{f.title: []byte(f.content)},
+				message: fmt.Sprintf("Add %s", f.title),
+			})
+			return fileCreatedMsg{gist: gist, file: f, response: response, err: err}
 		}
+		response, _, err := client.Gists.Edit(ctx, gistId, &g)
+		return fileCreatedMsg{gist: gist, file: f, response: response, err: err}
+	})
+
+	return cmds
+}
+
+func (m *model) fileCreated(msg fileCreatedMsg) []tea.Cmd {
+	var cmds []tea.Cmd
+	gist, f := msg.gist, msg.file
+
+	if msg.response != nil || msg.err != nil {
+		m.mainScreen.syncs.done(gist.id)
+	}
+	if msg.err != nil {
+		return syncFailed("could not create gist file", msg.err)
+	}
 
+	if response := msg.response; response != nil {
 		for _, file := range response.Files {
 			if file.GetFilename() == f.title {
 				f.gistId = response.GetID()
@@ -241,7 +285,7 @@ func (m *model) createFile(title string, gist *gist) []tea.Cmd {
 		"gistId":    f.gistId,
 		"content":   f.content,
 		"rawUrl":    f.rawUrl,
-		"stale":     f.stale,
+		"dirty":     f.dirty,
 		"updatedAt": f.updatedAt,
 		"draft":     f.draft,
 	})
@@ -254,185 +298,218 @@ func (m *model) createFile(title string, gist *gist) []tea.Cmd {
 	m.mainScreen.gists[gist] = append(m.mainScreen.gists[gist], f)
 
 	// update the file list with the new list
-	fileCmd := m.mainScreen.fileList.SetItems(m.mainScreen.gists[gist])
-	_, updateFileList := m.mainScreen.fileList.Update(nil)
-
-	cmds = append(cmds, fileCmd, updateFileList)
+	cmds = append(cmds, m.mainScreen.refreshFileList(gist)...)
 	cmds = append(cmds, showInfo("new file created", info_default))
 
 	return cmds
 }
 
-func (m *model) upload(pane pane) []tea.Cmd {
-	var cmds []tea.Cmd
-	gItem := m.mainScreen.gistList.SelectedItem()
-	g, _ := gItem.(*gist)
+type gistUploadedMsg struct {
+	gist *gist
+	// id the gist had before uploading, drafted gists get a new one from github
+	previousId string
+	wasDraft   bool
+	files      []file
+	response   *github.Gist
+	err        error
+	// part of an upload of every gist with local changes
+	batch bool
+}
 
-	if pane == PANE_FILES {
-		return cmds
+func (m *model) upload(pane pane) []tea.Cmd {
+	g := m.mainScreen.selectedGist()
+	if pane == PANE_FILES || g == nil {
+		return nil
 	}
+	// make sure the latest edits are part of the pending versions that get uploaded
+	cmds := m.mainScreen.stashEditor()
+	return append(cmds, m.transformUpload(uploadCheck{action: scan_upload, gists: []*gist{g}})...)
+}
 
-	var public bool
-	if g.visiblity == gist_public {
-		public = true
+// uploadGist starts uploading the gist, it reports whether the upload is underway
+func (m *model) uploadGist(g *gist, batch bool) ([]tea.Cmd, bool) {
+	files := []file{}
+	for _, item := range m.mainScreen.gists[g] {
+		f, _ := item.(file)
+		files = append(files, f)
 	}
-
-	gist := github.Gist{
-		Public:      &public,
-		Description: &g.name,
-		Files:       map[github.GistFilename]github.GistFile{},
+	files, err := withPendingContent(files)
+	if err != nil {
+		log.Errorf("could not load the pending changes of %q\n%v", g.name, err)
+		return []tea.Cmd{showInfo("could not load pending changes", info_error)}, false
 	}
 
-	files := []file{}
-	for _, item := range m.mainScreen.fileList.Items() {
-		file, _ := item.(file)
-		gist.Files[github.GistFilename(file.title)] = github.GistFile{
-			Filename: &file.title,
-			Content:  &file.content,
-		}
-		files = append(files, file)
+	ctx, tick, ok := m.mainScreen.syncs.begin(g.id)
+	if !ok {
+		return []tea.Cmd{showInfo("gist is still syncing", info_default)}, false
 	}
-	var response *github.Gist
-	if g.status == gist_status_drafted {
-		r, _, err := m.client.Gists.Create(context.Background(), &gist)
-		if err != nil {
-			log.Errorf("could not create gist on upload\n%w", err)
-			cmds = append(cmds, showInfo("could not create gist on upload", info_error))
-			return cmds
-		}
-		response = r
-		err = storage.db.Delete(query.NewQuery(string(collectionDraftedGists)).Where(query.Field("id").Eq(g.id)))
-		if err != nil {
-			log.Errorf("could not delete draft gist %q\n%w", g.name, err)
-			cmds = append(cmds, showInfo("could not create draft gist", info_error))
-			return nil
-		}
-	} else {
-		r, _, err := m.client.Gists.Edit(context.Background(), g.id, &gist)
-		if err != nil {
-			log.Errorf("could not update gist files\n%w", err)
-			cmds = append(cmds, showInfo("could not create draft gist", info_error))
-			return nil
-		}
-		response = r
-	}
-	var (
-		newGistId    string
-		newUpdatedAt time.Time
-	)
-	for _, respFile := range response.GetFiles() {
-		for i, dbFile := range files {
-			if dbFile.title == respFile.GetFilename() {
-				q := query.NewQuery(string(collectionGistContent)).
-					Where(query.Field("id").Eq(dbFile.id))
-				newGistId = response.GetID()
-				newUpdatedAt = response.GetUpdatedAt().In(time.Local)
-				updates := map[string]any{
-					"id":        dbFile.id,
-					"gistId":    response.GetID(),
-					"content":   respFile.GetContent(),
-					"updatedAt": newUpdatedAt.String(),
-					"draft":     false,
-					"rawUrl":    respFile.GetRawURL(),
-				}
 
-				files[i].gistId = updates["gistId"].(string)
-				files[i].content = updates["content"].(string)
-				files[i].updatedAt = updates["updatedAt"].(string)
-				files[i].draft = updates["draft"].(bool)
-				files[i].rawUrl = updates["rawUrl"].(string)
+	client := m.client
+	msg := gistUploadedMsg{
+		gist:       g,
+		previousId: g.id,
+		wasDraft:   g.status == gist_status_drafted,
+		files:      files,
+		batch:      batch,
+	}
+	sent := uploadedFiles(msg.wasDraft, files)
+	payload := gistPayload(g.name, g.visiblity, sent)
+	return []tea.Cmd{tick, func() tea.Msg {
+		msg.response, msg.err = sendGist(ctx, client, msg.previousId, msg.wasDraft, &payload, sent)
+		return msg
+	}}, true
+}
 
-				if err := storage.db.Update(q, updates); err != nil {
-					log.Errorf(
-						"could not update gist file %q in the collection\n%v",
-						respFile.GetFilename(), err,
-					)
+func (m *model) gistUploaded(msg gistUploadedMsg) []tea.Cmd {
+	var cmds []tea.Cmd
+	g, response := msg.gist, msg.response
 
-					return cmds
-				}
-				break
-			}
+	m.mainScreen.syncs.done(msg.previousId)
+	if msg.err != nil {
+		if msg.batch {
+			return m.mainScreen.uploadProgressed(g.name, msg.err)
+		}
+		if msg.wasDraft {
+			return syncFailed("could not create gist on upload", msg.err)
 		}
+		return syncFailed("could not update gist files", msg.err)
+	}
+
+	files, err := storeUploaded(msg.previousId, msg.wasDraft, msg.files, uploadedFiles(msg.wasDraft, msg.files), response)
+	if err != nil {
+		log.Errorf("could not store uploaded gist %q\n%v", g.name, err)
+		return []tea.Cmd{showInfo("could not store uploaded gist", info_error)}
 	}
 
 	updatedItems := make([]list.Item, len(files))
 	for idx, file := range files {
 		updatedItems[idx] = file
+		if m.mainScreen.editorFile == file.id {
+			m.mainScreen.editorSaved = file.content
+		}
 	}
 
-	if g.status == gist_status_drafted {
+	m.mainScreen.gists[g] = updatedItems
+	if msg.wasDraft {
 		g.status = gist_status_published
-		g.id = newGistId
-		g.updatedAt = newUpdatedAt
+		g.id = response.GetID()
+		g.htmlUrl = response.GetHTMLURL()
+		g.updatedAt = response.GetUpdatedAt().In(time.Local)
 
-		m.mainScreen.gists[g] = updatedItems
-		cmd := m.mainScreen.gistList.SetItem(m.mainScreen.gistList.Index(), g)
-		cmds = append(cmds, cmd)
-		m.mainScreen.gistList.Select(m.mainScreen.gistList.Index())
-	} else {
-		m.mainScreen.gists[g] = updatedItems
+		if idx := m.mainScreen.gistIndex(g); idx >= 0 {
+			cmds = append(cmds, m.mainScreen.gistList.SetItem(idx, g))
+		}
 	}
 
 	// update the file list so that we have the latest data
-	cmd := m.mainScreen.fileList.SetItems(updatedItems)
-	cmds = append(cmds, cmd)
-	_, updatedFileList := m.mainScreen.fileList.Update(nil)
-	cmds = append(cmds, updatedFileList)
+	cmds = append(cmds, m.mainScreen.refreshFileList(g)...)
+	if msg.batch {
+		return append(cmds, m.mainScreen.uploadProgressed(g.name, nil)...)
+	}
+	if skipped := skippedFiles(files); skipped > 0 {
+		return append(cmds, showInfo(fmt.Sprintf("gist uploaded, %d truncated file(s) were left out", skipped), info_default))
+	}
 	cmds = append(cmds, showInfo("gist uploaded", info_default))
 	return cmds
 }
 
+type fileDeletedMsg struct {
+	gist  *gist
+	files []file
+	err   error
+}
+
 func (m *model) deleteFile(g *gist) []tea.Cmd {
-	cmds := []tea.Cmd{}
 	f, ok := m.mainScreen.fileList.SelectedItem().(file)
 	if !ok || f.gistId != g.id {
 		log.Errorf("cannot get the selected file")
-		cmds = append(cmds, showInfo("cannot get the selected file", info_error))
-		return nil
+		return []tea.Cmd{showInfo("cannot get the selected file", info_error)}
 	}
+	return m.deleteFiles(g, []file{f})
+}
 
-	// uploaded file handling
-	if !f.draft {
-		gist := github.Gist{
-			Files: map[github.GistFilename]github.GistFile{
-				github.GistFilename(f.title): {},
-			},
-		}
-		_, _, err := m.client.Gists.Edit(context.Background(), g.id, &gist)
-		if err != nil {
-			log.Errorf("could not delete gist file %q from Github\n%w", f.title, err)
-			cmds = append(cmds, showInfo("could not delete gist file", info_error))
-			return nil
+// deleteFiles removes the files from the gist with a single request
+func (m *model) deleteFiles(g *gist, files []file) []tea.Cmd {
+	remote := []string{}
+	ids := []string{}
+	for _, f := range files {
+		// drafted file only lives in the collection
+		if !f.draft {
+			remote = append(remote, f.title)
 		}
+		ids = append(ids, f.id)
+	}
 
+	if len(remote) == 0 {
+		return []tea.Cmd{func() tea.Msg {
+			return fileDeletedMsg{gist: g, files: files}
+		}}
 	}
 
-	err := storage.db.Delete(query.NewQuery(string(collectionGistContent)).Where(query.Field("id").Eq(f.id)))
-	if err != nil {
-		cmds = append(cmds, showInfo("could not delete file from collection", info_error))
-		return nil
+	ctx, tick, ok := m.mainScreen.syncs.beginAll(ids)
+	if !ok {
+		return []tea.Cmd{showInfo("file is still syncing", info_default)}
 	}
 
-	// remove deleted file & update the gist file list with the new one
-	idx := m.mainScreen.fileList.Index()
-	m.mainScreen.fileList.RemoveItem(idx)
-	m.mainScreen.gists[g] = m.mainScreen.fileList.Items()
+	gist := github.Gist{
+		Files: map[github.GistFilename]github.GistFile{},
+	}
+	for _, title := range remote {
+		gist.Files[github.GistFilename(title)] = github.GistFile{}
+	}
+	client := m.client
+	gistId := g.id
+	return []tea.Cmd{tick, func() tea.Msg {
+		if cfg.GitBackend {
+			err := pushChange(ctx, gistId, gitChange{
+				remove:  remote,
+				message: fmt.Sprintf("Delete %s", strings.Join(remote, ", ")),
+			})
+			return fileDeletedMsg{gist: g, files: files, err: err}
+		}
+		_, _, err := client.Gists.Edit(ctx, gistId, &gist)
+		return fileDeletedMsg{gist: g, files: files, err: err}
+	}}
+}
 
-	// shift focus back to the previous deleted gist item
-	if len(m.mainScreen.fileList.Items()) > 0 {
-		if idx > 0 {
-			idx--
+func (m *model) fileDeleted(msg fileDeletedMsg) []tea.Cmd {
+	cmds := []tea.Cmd{}
+	g := msg.gist
+
+	for _, f := range msg.files {
+		m.mainScreen.syncs.done(f.id)
+	}
+	if msg.err != nil {
+		return syncFailed("could not delete gist file", msg.err)
+	}
+
+	for _, f := range msg.files {
+		err := storage.db.Delete(query.NewQuery(string(collectionGistContent)).Where(query.Field("id").Eq(f.id)))
+		if err != nil {
+			cmds = append(cmds, showInfo("could not delete file from collection", info_error))
+			return cmds
 		}
-		m.mainScreen.fileList.Select(idx)
+		cmds = append(cmds, m.mainScreen.removeFile(g, f.id)...)
 	}
 
-	_, updatedFileList := m.mainScreen.fileList.Update(nil)
-	cmds = append(cmds, updatedFileList)
-	cmds = append(cmds, showInfo("file deleted", info_default))
+	if len(msg.files) > 1 {
+		cmds = append(cmds, showInfo(fmt.Sprintf("%d files deleted", len(msg.files)), info_default))
+	} else {
+		cmds = append(cmds, showInfo("file deleted", info_default))
+	}
 
 	return cmds
 }
 
+type renamedMsg struct {
+	pane     pane
+	gist     *gist
+	file     file
+	value    string
+	response *github.Gist
+	err      error
+}
+
 func (m *model) rename(pane pane, newValue string) []tea.Cmd {
 	var cmds []tea.Cmd
 	gItem := m.mainScreen.gistList.SelectedItem()
@@ -442,36 +519,69 @@ func (m *model) rename(pane pane, newValue string) []tea.Cmd {
 		Files: map[github.GistFilename]github.GistFile{},
 	}
 
-	var selectedFile file
+	msg := renamedMsg{pane: pane, gist: selectedGist, value: newValue}
+	syncId := selectedGist.id
 	if pane == PANE_GISTS {
 		gist.Description = &newValue
 	} else {
 		fItem := m.mainScreen.fileList.SelectedItem()
 		f, _ := fItem.(file)
-		selectedFile = f
-		gist.Files[github.GistFilename(selectedFile.title)] = github.GistFile{
+		msg.file = f
+		syncId = f.id
+		gist.Files[github.GistFilename(f.title)] = github.GistFile{
 			Filename: &newValue,
 		}
 	}
 
-	var response *github.Gist
-	if selectedGist.status == gist_status_published {
-		r, _, err := m.client.Gists.Edit(context.Background(), selectedGist.id, &gist)
-		if err != nil {
-			log.Errorf("Error renaming gist with id %q\n%w", selectedGist.id, err)
-			cmds = append(cmds, showInfo("could not rename file", info_error))
-			return cmds
+	if selectedGist.status != gist_status_published {
+		return []tea.Cmd{func() tea.Msg { return msg }}
+	}
+
+	ctx, tick, ok := m.mainScreen.syncs.begin(syncId)
+	if !ok {
+		return []tea.Cmd{showInfo("item is still syncing", info_default)}
+	}
+
+	client := m.client
+	gistId := selectedGist.id
+	cmds = append(cmds, tick, func() tea.Msg {
+		// git has no notion of the gist description, that one always goes through the api
+		if cfg.GitBackend && pane == PANE_FILES {
+			msg.response, msg.err = pushGistChange(ctx, client, gistId, gitChange{
+				rename:  map[string]string{msg.file.title: newValue},
+				message: fmt.Sprintf("Rename %s to %s", msg.file.title, newValue),
+			})
+			return msg
+		}
+		msg.response, _, msg.err = client.Gists.Edit(ctx, gistId, &gist)
+		return msg
+	})
+	return cmds
+}
+
+func (m *model) renamed(msg renamedMsg) []tea.Cmd {
+	var cmds []tea.Cmd
+	selectedGist, selectedFile, newValue, response := msg.gist, msg.file, msg.value, msg.response
+	published := selectedGist.status == gist_status_published
+
+	if published {
+		if msg.pane == PANE_GISTS {
+			m.mainScreen.syncs.done(selectedGist.id)
+		} else {
+			m.mainScreen.syncs.done(selectedFile.id)
 		}
-		response = r
+	}
+	if msg.err != nil {
+		return syncFailed("could not rename file", msg.err)
 	}
 
-	if pane == PANE_GISTS {
-		if selectedGist.status == gist_status_drafted {
+	if msg.pane == PANE_GISTS {
+		if !published {
 			q := query.NewQuery(string(collectionDraftedGists)).Where(query.Field("id").Eq(selectedGist.id))
 			updates := map[string]any{}
 			updates["description"] = newValue
 			if err := storage.db.Update(q, updates); err != nil {
-				log.Errorf("Could not update renamed gist file %q\n%w", newValue, err)
+				log.Errorf("Could not update renamed gist file %q\n%v", newValue, err)
 				cmds = append(cmds, showInfo("could not rename file", info_error))
 				return cmds
 			}
@@ -480,42 +590,34 @@ func (m *model) rename(pane pane, newValue string) []tea.Cmd {
 			selectedGist.name = response.GetDescription()
 		}
 
-		idx := m.mainScreen.gistList.Index()
-		m.mainScreen.gistList.SetItem(idx, selectedGist)
-
-		cmds = append(cmds, m.mainScreen.gistList.SetItem(idx, selectedGist))
+		if idx := m.mainScreen.gistIndex(selectedGist); idx >= 0 {
+			cmds = append(cmds, m.mainScreen.gistList.SetItem(idx, selectedGist))
+		}
 	} else {
 		q := query.NewQuery(string(collectionGistContent)).Where(query.Field("id").Eq(selectedFile.id))
 		updates := map[string]any{}
 		updates["title"] = newValue
 		selectedFile.title = newValue
 
-		if selectedGist.status == gist_status_published {
+		if published {
 			for _, file := range response.GetFiles() {
 				if file.GetFilename() == newValue {
 					updates["rawUrl"] = file.GetRawURL()
 					updates["updatedAt"] = response.GetUpdatedAt().In(time.Local).String()
+					selectedFile.rawUrl = updates["rawUrl"].(string)
+					selectedFile.updatedAt = updates["updatedAt"].(string)
+					break
 				}
-				break
 			}
 		}
 
 		if err := storage.db.Update(q, updates); err != nil {
-			log.Errorf("Could not update renamed gist file %q\n%w", newValue, err)
+			log.Errorf("Could not update renamed gist file %q\n%v", newValue, err)
 			cmds = append(cmds, showInfo("could not rename file", info_error))
-			return nil
-		}
-
-		fileIdx := m.mainScreen.fileList.Index()
-		m.mainScreen.fileList.SetItem(fileIdx, selectedFile)
-
-		files := m.mainScreen.gists[selectedGist]
-		if fileIdx >= 0 && fileIdx < len(files) {
-			files[fileIdx] = selectedFile
+			return cmds
 		}
-		m.mainScreen.gists[selectedGist] = files
 
-		cmds = append(cmds, m.mainScreen.fileList.SetItem(fileIdx, selectedFile))
+		cmds = append(cmds, m.mainScreen.replaceFile(selectedGist, selectedFile)...)
 	}
 
 	cmds = append(cmds, showInfo("renamed successfully", info_default))
@@ -565,6 +667,10 @@ func (m *model) reInitDialog(msg tea.Msg, formType formType) tea.Cmd {
 	switch formType {
 	case form_type_create:
 		m.dialogScreen.state = dialog_create
+		if m.mainScreen.currentPane == PANE_FILES {
+			m.dialogScreen.form = m.dialogScreen.formFile()
+			break
+		}
 		m.dialogScreen.form = m.dialogScreen.formInput(actionType, "")
 	case form_type_rename:
 		m.dialogScreen.state = dialog_rename
@@ -584,7 +690,114 @@ func (m *model) reInitDialog(msg tea.Msg, formType formType) tea.Cmd {
 		m.dialogScreen.form = m.dialogScreen.formInput(actionType, value)
 	case form_type_delete:
 		m.dialogScreen.state = dialog_delete
-		m.dialogScreen.form = m.dialogScreen.formDelete()
+		var description string
+		switch m.mainScreen.currentPane {
+		case PANE_GISTS:
+			if n := len(m.mainScreen.marks.gists); n > 0 {
+				description = fmt.Sprintf("Delete %d marked gist(s)", n)
+			}
+		case PANE_FILES:
+			if n := len(m.mainScreen.marks.files); n > 0 {
+				description = fmt.Sprintf("Delete %d marked file(s)", n)
+			}
+		}
+		m.dialogScreen.form = m.dialogScreen.formDelete(description)
+	case form_type_move, form_type_copy:
+		source := m.mainScreen.selectedGist()
+		m.dialogScreen.state = dialog_move
+		verb := "Move"
+		if formType == form_type_copy {
+			m.dialogScreen.state = dialog_copy
+			verb = "Copy"
+		}
+		description := fmt.Sprintf("%s %d file(s) from %s", verb, len(m.mainScreen.marks.files), source.name)
+		if files := m.mainScreen.transferSelection(); len(files) == 1 {
+			description = fmt.Sprintf("%s %q from %s", verb, files[0].title, source.name)
+		}
+		m.dialogScreen.form = m.dialogScreen.formDelete(
+			description,
+			m.dialogScreen.gistPicker(m.mainScreen.targetOptions(source)),
+		)
+	case form_type_visibility:
+		targets := m.mainScreen.visibilityTargets()
+		if len(targets) == 0 {
+			return nil
+		}
+		m.dialogScreen.state = dialog_visibility
+		published := 0
+		for _, g := range targets {
+			if g.status == gist_status_published {
+				published++
+			}
+		}
+		description := fmt.Sprintf("Change the visibility of %s", targets[0].name)
+		if len(targets) > 1 {
+			description = fmt.Sprintf("Change the visibility of %d marked gist(s)", len(targets))
+		}
+		m.dialogScreen.form = m.dialogScreen.formVisibility(targets[0].visiblity, published, description)
+	case form_type_secrets:
+		pending := m.mainScreen.pendingSecrets
+		if pending == nil {
+			return nil
+		}
+		m.dialogScreen.state = dialog_secrets
+		m.dialogScreen.form = m.dialogScreen.formSecrets(countSecrets(pending.found), pending.verb())
+		m.dialogScreen.review = renderSecrets(pending.found, m.height-16, m.dialogScreen.styles.Diff)
+	case form_type_transforms:
+		pending := m.mainScreen.pendingTransforms
+		if pending == nil {
+			return nil
+		}
+		m.dialogScreen.state = dialog_transforms
+		m.dialogScreen.form = m.dialogScreen.formTransforms(len(pending.changed), pending.verb())
+		m.dialogScreen.review = renderTransforms(pending.changed, m.width-8, m.height-16, m.dialogScreen.styles.Diff)
+	case form_type_template:
+		pending := m.mainScreen.pendingTemplate
+		if pending == nil {
+			return nil
+		}
+		m.dialogScreen.state = dialog_template
+		m.dialogScreen.form = m.dialogScreen.formTemplate(pending.template)
+	case form_type_language:
+		f, ok := m.mainScreen.fileList.SelectedItem().(file)
+		if !ok {
+			return nil
+		}
+		m.dialogScreen.state = dialog_language
+		m.dialogScreen.form = m.dialogScreen.formLanguage(f.title, f.language)
+	case form_type_details:
+		f, ok := m.mainScreen.fileList.SelectedItem().(file)
+		g := m.mainScreen.selectedGist()
+		if !ok || g == nil {
+			return nil
+		}
+		m.dialogScreen.state = dialog_details
+		m.dialogScreen.form = m.dialogScreen.formDetails(f.title, f.details(g))
+	case form_type_links:
+		m.dialogScreen.state = dialog_links
+		m.dialogScreen.form = m.dialogScreen.formLinks(m.mainScreen.linkOptions())
+	case form_type_truncated:
+		m.dialogScreen.state = dialog_truncated
+		m.dialogScreen.form = m.dialogScreen.formConfirm(
+			"This file is truncated",
+			"Only part of it could be loaded, saving it will overwrite\nthe full content on Github with what you see.",
+			"Edit anyway",
+		)
+	case form_type_review:
+		pending := m.mainScreen.pendingSave
+		if pending == nil {
+			return nil
+		}
+		m.dialogScreen.state = dialog_review
+		m.dialogScreen.form = m.dialogScreen.formReview(pending.file.title)
+		m.dialogScreen.review = renderReview(*pending, m.width-8, m.height-12, m.dialogScreen.styles.Diff)
+	case form_type_quit:
+		m.dialogScreen.state = dialog_quit
+		m.dialogScreen.form = m.dialogScreen.formConfirm(
+			fmt.Sprintf("%d file(s) have unsaved changes", m.mainScreen.dirtyFiles()),
+			"They are kept as local drafts and restored the next time you open them.",
+			"Quit",
+		)
 	}
 
 	m.dialogScreen.form.WithShowHelp(true)
@@ -601,21 +814,37 @@ func (m model) Init() tea.Cmd {
 func (m model) View() string {
 	switch m.screenState {
 	case authScreen:
-		return m.authScreen.View()
+		return m.escapes + m.authScreen.View()
 	case mainScreen:
-		return m.mainScreen.View()
+		return m.escapes + m.mainScreen.View()
 	case dialogScreen:
-		return m.dialogScreen.View()
+		return m.escapes + m.dialogScreen.View()
 	}
 	return "no view defined for this state"
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	updated, cmd := m.update(msg)
+	m = updated.(model)
+	// the kitty image lives outside of the rendered text, it follows whatever the update left on screen
+	return m, tea.Batch(cmd, m.syncImage())
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	var cmds []tea.Cmd
 
 	switch msg := msg.(type) {
 
+	case escapeMsg:
+		return m, m.queueEscape(string(msg))
+
+	case escapesSentMsg:
+		if msg.gen == m.escapesGen {
+			m.escapes = ""
+		}
+		return m, nil
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -624,18 +853,78 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.screenState == mainScreen || m.screenState == dialogScreen {
 			switch msg.String() {
 			case "ctrl+c":
+				// pressing it again while asked to confirm quits right away
+				if m.screenState == dialogScreen && m.dialogScreen.state == dialog_quit {
+					return m, tea.Quit
+				}
+				cmds = append(cmds, m.mainScreen.stashEditor()...)
+				if m.screenState == mainScreen && m.mainScreen.dirtyFiles() > 0 {
+					// the prompt has to open from the editor too, where dialogs are disabled otherwise
+					m.dialogState = dialog_closed
+					cmds = append(cmds, m.reInitDialog(msg, form_type_quit))
+					return m, tea.Batch(cmds...)
+				}
 				return m, tea.Quit
+			case "ctrl+x":
+				if n := m.mainScreen.syncs.cancelAll(); n > 0 {
+					return m, showInfo(fmt.Sprintf("cancelling %d request(s)", n), info_default)
+				}
+				return m, nil
 			case "u":
 				if m.mainScreen.currentPane != PANE_EDITOR && m.screenState != dialogScreen {
 					cmds = append(cmds, m.upload(m.mainScreen.currentPane)...)
 					return m, tea.Batch(cmds...)
 				}
+			case "U":
+				if m.mainScreen.currentPane != PANE_EDITOR && m.screenState != dialogScreen {
+					cmds = append(cmds, m.uploadAll()...)
+					return m, tea.Batch(cmds...)
+				}
 			case "a":
 				return m, m.reInitDialog(msg, form_type_create)
 			case "r":
 				return m, m.reInitDialog(msg, form_type_rename)
 			case "d":
 				return m, m.reInitDialog(msg, form_type_delete)
+			case "v":
+				if m.screenState == mainScreen && m.dialogState == dialog_closed && m.mainScreen.currentPane != PANE_GISTS {
+					return m, showInfo("visibility can only be changed from the gists pane", info_default)
+				}
+				return m, m.reInitDialog(msg, form_type_visibility)
+			case "L":
+				if m.screenState == mainScreen && m.dialogState == dialog_closed && m.mainScreen.currentPane != PANE_FILES {
+					return m, showInfo("the language can only be set from the files pane", info_default)
+				}
+				return m, m.reInitDialog(msg, form_type_language)
+			case "i":
+				if m.screenState == mainScreen && m.dialogState == dialog_closed && m.mainScreen.currentPane != PANE_FILES {
+					return m, showInfo("details are shown for files, select one in the files pane", info_default)
+				}
+				return m, m.reInitDialog(msg, form_type_details)
+			case "o":
+				if m.mainScreen.currentPane != PANE_EDITOR && m.screenState != dialogScreen {
+					return m, m.mainScreen.openGist()
+				}
+			case "Y":
+				if !m.mainScreen.keymap.CopyLink.Enabled() {
+					break
+				}
+				if m.screenState == mainScreen && m.dialogState == dialog_closed {
+					if _, err := m.mainScreen.selectedLinks(); err != nil {
+						return m, showInfo(err.Error(), info_default)
+					}
+				}
+				return m, m.reInitDialog(msg, form_type_links)
+			case "m", "c":
+				if m.screenState == mainScreen && m.dialogState == dialog_closed {
+					if cmd := m.mainScreen.guardTransfer(); cmd != nil {
+						return m, cmd
+					}
+				}
+				if msg.String() == "m" {
+					return m, m.reInitDialog(msg, form_type_move)
+				}
+				return m, m.reInitDialog(msg, form_type_copy)
 			case "esc":
 				if m.screenState == dialogScreen {
 					m.closeDialog()
@@ -654,6 +943,40 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case clearInfoMsg:
 		m.infoMsg = nil
 
+	case spinner.TickMsg:
+		if m.screenState != authScreen {
+			return m, m.mainScreen.syncs.update(msg)
+		}
+
+	case gistDeletedMsg:
+		return m, tea.Batch(m.gistDeleted(msg)...)
+	case fileCreatedMsg:
+		return m, tea.Batch(m.fileCreated(msg)...)
+	case gistUploadedMsg:
+		return m, tea.Batch(m.gistUploaded(msg)...)
+	case secretsFoundMsg:
+		return m, m.secretsFound(msg)
+	case transformsMsg:
+		return m, m.transformsFound(msg)
+	case templateChosenMsg:
+		return m, m.templateChosen(msg)
+	case visibilityChangedMsg:
+		return m, tea.Batch(m.visibilityChanged(msg)...)
+	case filesTransferredMsg:
+		return m, tea.Batch(m.filesTransferred(msg)...)
+	case fileDeletedMsg:
+		return m, tea.Batch(m.fileDeleted(msg)...)
+	case renamedMsg:
+		return m, tea.Batch(m.renamed(msg)...)
+	case fileSavedMsg:
+		return m, tea.Batch(m.mainScreen.fileSaved(msg)...)
+	case confirmTruncatedMsg:
+		return m, m.reInitDialog(msg, form_type_truncated)
+	case saveReviewMsg:
+		return m, m.saveReviewed(msg)
+	case prefetchProgressMsg:
+		return m, tea.Batch(m.mainScreen.prefetched(msg)...)
+
 	case authSuccessMsg:
 		m.client = msg.client
 		model := newMainModel(msg.client)
@@ -673,7 +996,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Batch(cmds...)
 
 	case dialogStateChangeMsg:
-		m.dialogState = dialogState(msg)
+		// a pane change landing after a dialog got opened by an async result must not close it again
+		if m.dialogState != dialog_opened || m.screenState != dialogScreen {
+			m.dialogState = dialogState(msg)
+		}
 
 	case dialogSubmitMsg:
 		selectedGist := m.mainScreen.gistList.SelectedItem()
@@ -685,25 +1011,91 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		state := msg.state
 		pane := m.mainScreen.currentPane
+		if state == dialog_quit {
+			return m, tea.Quit
+		}
 
 		switch state {
 		case dialog_create:
-			if pane == PANE_GISTS {
-				cmds = append(cmds, m.createGist(msg.value, msg.gistVisibility)...)
+			if pane == PANE_GISTS && msg.template != "" {
+				cmds = append(cmds, m.chooseTemplate(msg.value, msg.gistVisibility, msg.template)...)
+			} else if pane == PANE_GISTS {
+				_, created := m.createGist(msg.value, msg.gistVisibility)
+				cmds = append(cmds, created...)
+				cmds = append(cmds, showInfo("new gist created", info_default))
 			} else {
-				cmds = append(cmds, m.createFile(msg.value, gist)...)
+				cmds = append(cmds, m.createFile(msg.value, msg.language, gist)...)
 			}
 			break
 		case dialog_delete:
-			if pane == PANE_GISTS {
+			marks := m.mainScreen.marks
+			switch {
+			case pane == PANE_GISTS && len(marks.gists) > 0:
+				for _, g := range marks.markedGists() {
+					cmds = append(cmds, m.deleteGist(g)...)
+				}
+				marks.clear()
+			case pane == PANE_GISTS:
 				cmds = append(cmds, m.deleteGist(gist)...)
-			} else {
+			case len(marks.files) > 0:
+				cmds = append(cmds, m.deleteFiles(gist, marks.markedFiles(m.mainScreen.gists[gist]))...)
+				marks.clearFiles()
+			default:
 				cmds = append(cmds, m.deleteFile(gist)...)
 			}
 			break
+		case dialog_move, dialog_copy:
+			target := m.mainScreen.gistById(msg.target)
+			if target == nil {
+				cmds = append(cmds, showInfo("could not find the target gist", info_error))
+				break
+			}
+			files := m.mainScreen.transferSelection()
+			cmds = append(cmds, m.transferFiles(gist, files, target, state == dialog_move)...)
+			break
+		case dialog_visibility:
+			// local changes are part of the re-created gists
+			cmds = append(cmds, m.mainScreen.stashEditor()...)
+			for _, g := range m.mainScreen.visibilityTargets() {
+				cmds = append(cmds, m.changeVisibility(g, msg.gistVisibility, msg.deleteOriginal)...)
+			}
+			m.mainScreen.marks.clear()
+			break
+		case dialog_secrets:
+			cmds = append(cmds, m.secretsReviewed(msg.value == "redact")...)
+			break
+		case dialog_template:
+			if pending := m.mainScreen.pendingTemplate; pending != nil {
+				m.mainScreen.pendingTemplate = nil
+				cmds = append(cmds, m.createFromTemplate(pending.name, pending.visibility, pending.template, msg.variables)...)
+			}
+			break
+		case dialog_transforms:
+			cmds = append(cmds, m.transformsReviewed(msg.value == "apply")...)
+			break
+		case dialog_language:
+			if f, ok := m.mainScreen.fileList.SelectedItem().(file); ok {
+				cmds = append(cmds, m.mainScreen.setLanguage(gist, f, msg.value)...)
+			}
+			break
+		case dialog_details:
+			break
+		case dialog_links:
+			cmds = append(cmds, m.mainScreen.copyLink(msg.value))
+			break
 		case dialog_rename:
 			cmds = append(cmds, m.rename(pane, msg.value)...)
 			break
+		case dialog_truncated:
+			m.mainScreen.truncatedAck = m.mainScreen.editorFile
+			m.mainScreen.currentPane = PANE_EDITOR
+			break
+		case dialog_review:
+			if pending := m.mainScreen.pendingSave; pending != nil {
+				cmds = append(cmds, m.mainScreen.saveFile(pending.gist, pending.file, pending.content)...)
+				m.mainScreen.pendingSave = nil
+			}
+			break
 		default:
 			log.Errorf("Unrecognized dialog state %q\n", state)
 			return m, nil
@@ -713,6 +1105,20 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.closeDialog()
 		return m, tea.Batch(cmds...)
 	case dialogCancelled:
+		if m.dialogScreen.state == dialog_review {
+			cmds = append(cmds, m.mainScreen.discardReview()...)
+		}
+		if m.dialogScreen.state == dialog_secrets {
+			m.mainScreen.pendingSecrets = nil
+			cmds = append(cmds, showInfo(err_secrets_found.Error(), info_default))
+		}
+		if m.dialogScreen.state == dialog_template {
+			m.mainScreen.pendingTemplate = nil
+		}
+		if m.dialogScreen.state == dialog_transforms {
+			m.mainScreen.pendingTransforms = nil
+			cmds = append(cmds, showInfo(err_transform_cancelled.Error(), info_default))
+		}
 		cmds = append(cmds, m.mainScreen.updateActivePane(msg)...)
 		m.closeDialog()
 		return m, tea.Batch(cmds...)
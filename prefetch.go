@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// amount of raw files downloaded at the same time
+const prefetchWorkers = 4
+
+type prefetchJob struct {
+	gist *gist
+	file file
+}
+
+type prefetchResult struct {
+	job       prefetchJob
+	content   string
+	truncated bool
+	err       error
+}
+
+type prefetchProgressMsg struct {
+	prefetchResult
+	done  int
+	total int
+
+	// channel the next progress message arrives from
+	progress <-chan prefetchProgressMsg
+}
+
+type prefetchProgress struct {
+	done   int
+	total  int
+	failed int
+}
+
+// startPrefetch downloads every missing or stale published file in the background, so moving
+// through the file list doesn't have to wait for the network
+func (m *mainModel) startPrefetch() tea.Cmd {
+	jobs := []prefetchJob{}
+	for g, items := range m.gists {
+		for _, item := range items {
+			f, ok := item.(file)
+			if !ok || f.draft {
+				continue
+			}
+			existing, shouldFetch, err := f.cachedContent()
+			if err != nil || existing == nil || !shouldFetch {
+				continue
+			}
+			jobs = append(jobs, prefetchJob{gist: g, file: f})
+		}
+	}
+
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	return func() tea.Msg {
+		progress := make(chan prefetchProgressMsg)
+		go runPrefetch(jobs, progress)
+		return <-progress
+	}
+}
+
+func runPrefetch(jobs []prefetchJob, progress chan prefetchProgressMsg) {
+	queue := make(chan prefetchJob)
+	results := make(chan prefetchResult)
+
+	var wg sync.WaitGroup
+	for range min(prefetchWorkers, len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				result := prefetchResult{job: job}
+				existing, shouldFetch, err := job.file.cachedContent()
+				switch {
+				case err != nil:
+					result.err = err
+				case existing == nil:
+					result.err = fmt.Errorf("could not find %q in the collection", job.file.title)
+				case !shouldFetch:
+					// the file got fetched while it was waiting in the queue
+					result.content, _ = existing.Get("content").(string)
+					result.truncated, _ = existing.Get("truncated").(bool)
+				default:
+					result.content, result.truncated, result.err = job.file.fetchContent()
+				}
+				results <- result
+			}
+		}()
+	}
+
+	go func() {
+		for _, job := range jobs {
+			queue <- job
+		}
+		close(queue)
+		wg.Wait()
+		close(results)
+	}()
+
+	// forward results one by one so the progress count stays in order
+	done := 0
+	for result := range results {
+		done++
+		progress <- prefetchProgressMsg{
+			prefetchResult: result,
+			done:           done,
+			total:          len(jobs),
+			progress:       progress,
+		}
+	}
+	close(progress)
+}
+
+func (m *mainModel) prefetched(msg prefetchProgressMsg) []tea.Cmd {
+	var cmds []tea.Cmd
+
+	m.prefetch.done, m.prefetch.total = msg.done, msg.total
+	if msg.err != nil {
+		m.prefetch.failed++
+		log.Errorf("could not prefetch %q\n%v", msg.job.file.title, msg.err)
+	} else {
+		// keep the in memory file in sync, uploads send whatever content it holds
+		if idx := fileIndex(m.gists[msg.job.gist], msg.job.file.id); idx >= 0 {
+			f, _ := m.gists[msg.job.gist][idx].(file)
+			f.content = msg.content
+			f.truncated = msg.truncated
+			cmds = append(cmds, m.replaceFile(msg.job.gist, f)...)
+		}
+	}
+
+	if msg.done < msg.total {
+		progress := msg.progress
+		cmds = append(cmds, func() tea.Msg {
+			return <-progress
+		})
+		return cmds
+	}
+
+	if m.prefetch.failed > 0 {
+		cmds = append(cmds, showInfo(fmt.Sprintf("could not prefetch %d file(s)", m.prefetch.failed), info_error))
+	} else {
+		cmds = append(cmds, showInfo(fmt.Sprintf("prefetched %d file(s)", msg.total), info_default))
+	}
+	m.prefetch = prefetchProgress{}
+	return cmds
+}
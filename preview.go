@@ -0,0 +1,118 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/ansi"
+	glamourstyles "github.com/charmbracelet/glamour/styles"
+)
+
+func isMarkdown(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// markdownStyle derives the glamour style from the chroma theme the editor uses,
+// so the preview and the editor share the same palette
+func markdownStyle() ansi.StyleConfig {
+	theme := styles.Get(cfg.Theme)
+
+	style := glamourstyles.DarkStyleConfig
+	if bg := theme.Get(chroma.Background).Background; bg.IsSet() && bg.Brightness() > 0.5 {
+		style = glamourstyles.LightStyleConfig
+	}
+
+	color := func(token chroma.TokenType) *string {
+		entry := theme.Get(token)
+		if !entry.Colour.IsSet() {
+			return nil
+		}
+		c := entry.Colour.String()
+		return &c
+	}
+	bold := true
+
+	style.Document.Color = color(chroma.Text)
+	style.Heading.Color = color(chroma.GenericHeading)
+	style.H1.Color = color(chroma.GenericHeading)
+	style.H1.BackgroundColor = nil
+	style.H1.Bold = &bold
+	style.H2.Color = color(chroma.GenericSubheading)
+	style.Link.Color = color(chroma.NameAttribute)
+	style.LinkText.Color = color(chroma.NameFunction)
+	style.BlockQuote.Color = color(chroma.Comment)
+	style.Code.Color = color(chroma.LiteralString)
+	style.Code.BackgroundColor = nil
+	style.Item.Color = color(chroma.Keyword)
+	style.Enumeration.Color = color(chroma.Keyword)
+
+	// code blocks are highlighted with the chroma theme itself instead of the glamour palette
+	style.CodeBlock.Chroma = nil
+	style.CodeBlock.Theme = cfg.Theme
+
+	return style
+}
+
+func newMarkdownRenderer(width int) (*glamour.TermRenderer, error) {
+	return glamour.NewTermRenderer(
+		glamour.WithStyles(markdownStyle()),
+		glamour.WithWordWrap(max(width-4, 20)),
+	)
+}
+
+// markdownPreview renders the editor content next to the editor
+type markdownPreview struct {
+	enabled bool
+	port    viewport.Model
+
+	// content and width the viewport was last rendered for, rendering is skipped when neither changed
+	content string
+	width   int
+
+	// building a renderer parses the whole style, so it is kept until the width changes
+	renderer      *glamour.TermRenderer
+	rendererWidth int
+}
+
+func (p *markdownPreview) renderMarkdown(content string, width int) (string, error) {
+	if p.renderer == nil || p.rendererWidth != width {
+		r, err := newMarkdownRenderer(width)
+		if err != nil {
+			return "", err
+		}
+		p.renderer, p.rendererWidth = r, width
+	}
+	return p.renderer.Render(content)
+}
+
+func (p *markdownPreview) render(content string, width, height int) {
+	p.port.Height = height
+	if content == p.content && width == p.width {
+		return
+	}
+	p.port.Width = width
+
+	out, err := p.renderMarkdown(content, width)
+	if err != nil {
+		log.Errorf("could not render markdown preview\n%v", err)
+		out = content
+	}
+	p.port.SetContent(out)
+	p.content, p.width = content, width
+}
+
+// follow scrolls the preview to the part matching the given line of the source, headings and
+// code blocks render to a different amount of lines so the position is scaled instead of copied
+func (p *markdownPreview) follow(line int) {
+	lines := strings.Count(p.content, "\n") + 1
+	target := line * p.port.TotalLineCount() / lines
+	p.port.SetYOffset(target - p.port.Height/2)
+}
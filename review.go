@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/go-github/v74/github"
+)
+
+// saveReviewMsg carries what is needed to review a save before it gets pushed
+type saveReviewMsg struct {
+	gist    *gist
+	file    file
+	content string
+	// content the editor was loaded with
+	cached string
+	// content currently on github, only set when it differs from the cached content
+	remote        string
+	remoteChanged bool
+	// the remote could not be checked, the review only compares against the cached content
+	remoteErr error
+	err       error
+}
+
+// reviewSave fetches the latest revision of the file so the save can be reviewed against it
+func (m *mainModel) reviewSave(g *gist, f file, content string) []tea.Cmd {
+	ctx, tick, ok := m.syncs.begin(f.id)
+	if !ok {
+		return []tea.Cmd{showInfo("file is still syncing", info_default)}
+	}
+
+	cached := f.content
+	if existing, _, err := f.cachedContent(); err == nil && existing != nil {
+		if c, ok := existing.Get("content").(string); ok {
+			cached = c
+		}
+	}
+
+	client := m.client
+	return []tea.Cmd{tick, func() tea.Msg {
+		msg := saveReviewMsg{gist: g, file: f, content: content, cached: cached}
+
+		remote, _, err := client.Gists.Get(ctx, g.id)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				msg.err = err
+			} else {
+				msg.remoteErr = err
+			}
+			return msg
+		}
+
+		remoteFile, ok := remote.GetFiles()[github.GistFilename(f.title)]
+		if !ok {
+			// somebody removed the file in the meantime, saving brings it back
+			msg.remoteChanged = true
+			return msg
+		}
+		msg.remote = remoteFile.GetContent()
+		if apiContentTruncated(remoteFile) {
+			msg.remote, msg.remoteErr = file{title: f.title, rawUrl: remoteFile.GetRawURL()}.fetchRaw()
+			if msg.remoteErr != nil {
+				return msg
+			}
+		}
+		msg.remoteChanged = msg.remote != cached
+		return msg
+	}}
+}
+
+func (m *model) saveReviewed(msg saveReviewMsg) tea.Cmd {
+	m.mainScreen.syncs.done(msg.file.id)
+	if msg.err != nil {
+		return tea.Batch(syncFailed("could not review changes", msg.err)...)
+	}
+	if msg.remoteErr != nil {
+		log.Errorf("could not check %q for newer changes\n%v", msg.file.title, msg.remoteErr)
+	}
+	if msg.content == msg.cached && !msg.remoteChanged {
+		return showInfo("no changes to save", info_default)
+	}
+
+	m.mainScreen.pendingSave = &msg
+	return m.reInitDialog(msg, form_type_review)
+}
+
+// discardReview drops the reviewed changes and puts the content the editor was loaded with back
+func (m *mainModel) discardReview() []tea.Cmd {
+	pending := m.pendingSave
+	m.pendingSave = nil
+	if pending == nil {
+		return nil
+	}
+	var cmds []tea.Cmd
+	if m.editorFile == pending.file.id {
+		m.editor.SetContent(pending.cached)
+		m.syncPreview()
+		cmds = append(cmds, m.stashEditor()...)
+	}
+	return append(cmds, showInfo("changes discarded", info_default))
+}
+
+// renderReview shows the changes made in the editor, and the changes made on github since the file was loaded
+func renderReview(review saveReviewMsg, width, height int, styles DiffStyle) string {
+	var sections []string
+
+	local := diffLines(review.cached, review.content)
+	budget := height
+	if review.remoteChanged {
+		budget = height/2 - 1
+	}
+
+	sections = append(sections, styles.Header.Render("Your changes"))
+	if hasChanges(local) {
+		sections = append(sections, renderSideBySide(local, width, budget-1, styles))
+	} else {
+		sections = append(sections, styles.Gutter.Render("no changes in the editor"))
+	}
+
+	switch {
+	case review.remoteErr != nil:
+		sections = append(sections, "", styles.Warning.Render("Could not check Github for newer changes"))
+	case review.remoteChanged:
+		sections = append(sections,
+			"",
+			styles.Warning.Render("Changed on Github since it was loaded, saving overwrites the left side"),
+			renderSideBySide(diffLines(review.remote, review.content), width, height-budget-3, styles),
+		)
+	}
+
+	return lipgloss.NewStyle().Align(lipgloss.Left).Render(strings.Join(sections, "\n"))
+}
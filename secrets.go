@@ -0,0 +1,556 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var err_secrets_found = errors.New("possible secrets found, nothing was uploaded")
+
+// secretRule flags whatever the pattern matches, or only its first group when it has one
+type secretRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+type secretScanConfig struct {
+	Disabled bool `json:"disabled"`
+	// only scan content that goes to public gists
+	OnlyPublic bool `json:"onlyPublic"`
+	// names of built-in rules to leave out, "high-entropy" turns the entropy check off
+	Ignore []string `json:"ignore"`
+	// rules added to the built-in ones
+	Rules []secretRule `json:"rules"`
+	// patterns of values that are never reported, like example keys
+	Allow []string `json:"allow"`
+	// bits of entropy per character a long token needs to be reported, 0 uses the default
+	Entropy float64 `json:"entropy"`
+}
+
+var builtinSecretRules = []secretRule{
+	{Name: "aws-access-key", Pattern: `\b(?:AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16}\b`},
+	{Name: "aws-secret-key", Pattern: `(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`},
+	{Name: "github-token", Pattern: `\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`},
+	{Name: "private-key", Pattern: `-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----(?:[\s\S]*?-----END (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----)?`},
+	{Name: "env-assignment", Pattern: `(?im)^[ \t]*(?:export[ \t]+)?[A-Z0-9_]*(?:SECRET|TOKEN|PASSWORD|PASSWD|PWD|API_?KEY|ACCESS_?KEY|PRIVATE_?KEY|CREDENTIALS?)[A-Z0-9_]*[ \t]*=[ \t]*["']?([^\s"'#]{6,})`},
+}
+
+const (
+	highEntropyRule    = "high-entropy"
+	defaultEntropy     = 4.5
+	minEntropyTokenLen = 20
+)
+
+var entropyToken = regexp.MustCompile(`[A-Za-z0-9+/_\-]{20,}={0,2}`)
+
+type compiledRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+type secretScanner struct {
+	rules   []compiledRule
+	allow   []*regexp.Regexp
+	entropy float64
+}
+
+// secretMatch is a possible secret, start and end are byte offsets into the scanned content
+type secretMatch struct {
+	rule  string
+	line  int
+	start int
+	end   int
+	value string
+}
+
+func newSecretScanner(c secretScanConfig) (*secretScanner, error) {
+	s := &secretScanner{entropy: c.Entropy}
+	if s.entropy <= 0 {
+		s.entropy = defaultEntropy
+	}
+	if slices.Contains(c.Ignore, highEntropyRule) {
+		s.entropy = 0
+	}
+
+	for _, rule := range append(slices.Clone(builtinSecretRules), c.Rules...) {
+		if slices.Contains(c.Ignore, rule.Name) {
+			continue
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret rule %q: %w", rule.Name, err)
+		}
+		s.rules = append(s.rules, compiledRule{name: rule.Name, pattern: pattern})
+	}
+	for _, allowed := range c.Allow {
+		pattern, err := regexp.Compile(allowed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed secret pattern %q: %w", allowed, err)
+		}
+		s.allow = append(s.allow, pattern)
+	}
+	return s, nil
+}
+
+// scan reports the possible secrets in content sorted by offset, overlapping matches are only reported once
+func (s *secretScanner) scan(content string) []secretMatch {
+	if isBinary([]byte(content)) {
+		return nil
+	}
+
+	var matches []secretMatch
+	for _, rule := range s.rules {
+		for _, loc := range rule.pattern.FindAllStringSubmatchIndex(content, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) >= 4 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			matches = append(matches, secretMatch{rule: rule.name, start: start, end: end})
+		}
+	}
+	if s.entropy > 0 {
+		for _, loc := range entropyToken.FindAllStringIndex(content, -1) {
+			token := content[loc[0]:loc[1]]
+			if mixedToken(token) && shannonEntropy(token) >= s.entropy {
+				matches = append(matches, secretMatch{rule: highEntropyRule, start: loc[0], end: loc[1]})
+			}
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b secretMatch) int {
+		if a.start != b.start {
+			return a.start - b.start
+		}
+		return b.end - a.end
+	})
+
+	reported := []secretMatch{}
+	for _, match := range matches {
+		if n := len(reported); n > 0 && match.start < reported[n-1].end {
+			continue
+		}
+		match.value = content[match.start:match.end]
+		if s.allowed(match.value) {
+			continue
+		}
+		match.line = strings.Count(content[:match.start], "\n") + 1
+		reported = append(reported, match)
+	}
+	return reported
+}
+
+func (s *secretScanner) allowed(value string) bool {
+	for _, pattern := range s.allow {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// mixedToken keeps identifiers and words out of the entropy check, random tokens mix letters and digits
+func mixedToken(token string) bool {
+	var letter, digit bool
+	for _, r := range token {
+		letter = letter || unicode.IsLetter(r)
+		digit = digit || unicode.IsDigit(r)
+	}
+	return letter && digit && len(token) >= minEntropyTokenLen
+}
+
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	entropy := 0.0
+	total := float64(len([]rune(s)))
+	for _, count := range counts {
+		p := float64(count) / total
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// redactSecrets replaces every match with a marker naming the rule that caught it
+func redactSecrets(content string, matches []secretMatch) string {
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		b.WriteString(content[last:match.start])
+		fmt.Fprintf(&b, "REDACTED(%s)", match.rule)
+		last = match.end
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// maskSecret shows just enough of a secret to recognize it
+func maskSecret(value string) string {
+	value = strings.SplitN(value, "\n", 2)[0]
+	runes := []rune(value)
+	if len(runes) <= 8 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:4]) + "…" + string(runes[len(runes)-2:])
+}
+
+// scannedFile holds the possible secrets of a file about to be uploaded
+type scannedFile struct {
+	gist    *gist
+	file    file
+	content string
+	matches []secretMatch
+}
+
+func (f scannedFile) redacted() string {
+	return redactSecrets(f.content, f.matches)
+}
+
+// shouldScan tells whether content headed to a gist with the given visibility has to be scanned
+func shouldScan(visibility gistVisibility) bool {
+	if cfg.SecretScan.Disabled {
+		return false
+	}
+	return !cfg.SecretScan.OnlyPublic || visibility == gist_public
+}
+
+// scanFiles scans the content of every file, only files with possible secrets are returned
+func scanFiles(g *gist, files []file) ([]scannedFile, error) {
+	scanner, err := newSecretScanner(cfg.SecretScan)
+	if err != nil {
+		return nil, err
+	}
+	var found []scannedFile
+	for _, f := range files {
+		if matches := scanner.scan(f.content); len(matches) > 0 {
+			found = append(found, scannedFile{gist: g, file: f, content: f.content, matches: matches})
+		}
+	}
+	return found, nil
+}
+
+// describeSecrets lists the possible secrets, one per line
+func describeSecrets(found []scannedFile) []string {
+	var lines []string
+	for _, f := range found {
+		for _, match := range f.matches {
+			lines = append(lines, fmt.Sprintf("%s:%d  %s  %s", f.file.title, match.line, match.rule, maskSecret(match.value)))
+		}
+	}
+	return lines
+}
+
+func countSecrets(found []scannedFile) int {
+	count := 0
+	for _, f := range found {
+		count += len(f.matches)
+	}
+	return count
+}
+
+// promptSecrets lets the command line decide what happens to the possible secrets,
+// it reports whether they have to be redacted and fails when the upload is aborted
+func promptSecrets(reader *bufio.Reader, found []scannedFile) (bool, error) {
+	fmt.Printf("Found %d possible secret(s):\n", countSecrets(found))
+	for _, line := range describeSecrets(found) {
+		fmt.Println("  " + line)
+	}
+
+	for {
+		fmt.Print("[r]edact, [u]pload anyway or [a]bort? ")
+		answer, err := reader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "r", "redact":
+			return true, nil
+		case "u", "upload":
+			return false, nil
+		case "a", "abort":
+			return false, err_secrets_found
+		}
+		if err != nil {
+			// nobody is there to answer, better safe than sorry
+			fmt.Println()
+			return false, err_secrets_found
+		}
+	}
+}
+
+// confirmSecrets scans the files headed to a gist from the command line, and redacts them when asked to
+func confirmSecrets(visibility gistVisibility, files []file) ([]file, error) {
+	if !shouldScan(visibility) {
+		return files, nil
+	}
+	found, err := scanFiles(nil, files)
+	if err != nil || len(found) == 0 {
+		return files, err
+	}
+	redact, err := promptSecrets(stdin, found)
+	if err != nil || !redact {
+		return files, err
+	}
+	return redactFiles(files, found), nil
+}
+
+// redactFiles returns the files with the possible secrets found in them redacted
+func redactFiles(files []file, found []scannedFile) []file {
+	redacted := slices.Clone(files)
+	for _, f := range found {
+		for i := range redacted {
+			if redacted[i].id == f.file.id && redacted[i].title == f.file.title {
+				redacted[i].content = f.redacted()
+			}
+		}
+	}
+	return redacted
+}
+
+type scanAction int
+
+const (
+	scan_upload scanAction = iota
+	scan_upload_all
+	scan_save
+	scan_visibility
+	scan_transfer
+)
+
+// uploadCheck is an upload or save held back until what is about to reach github got checked
+type uploadCheck struct {
+	action scanAction
+	// gists about to be uploaded
+	gists []*gist
+	// file about to be saved
+	gist    *gist
+	file    file
+	content string
+	// files about to be re-created with another visibility or transferred to the target, already loaded
+	files          []file
+	visibility     gistVisibility
+	deleteOriginal bool
+	target         *gist
+	move           bool
+}
+
+func (c uploadCheck) verb() string {
+	switch c.action {
+	case scan_save:
+		return "save"
+	case scan_visibility:
+		return "re-create"
+	case scan_transfer:
+		if c.move {
+			return "move"
+		}
+		return "copy"
+	}
+	return "upload"
+}
+
+// scanTransfer scans files that are loaded in the background and about to be sent to a gist with the given visibility
+func scanTransfer(g *gist, visibility gistVisibility, files []file) ([]scannedFile, error) {
+	if !shouldScan(visibility) {
+		return nil, nil
+	}
+	return scanFiles(g, files)
+}
+
+// release gives up the syncs a check held while its content was loaded, nothing is sent until the user decided
+func (c uploadCheck) release(syncs *syncTracker) {
+	switch c.action {
+	case scan_visibility:
+		syncs.done(c.gist.id)
+	case scan_transfer:
+		syncs.done(c.target.id)
+		for _, f := range c.files {
+			syncs.done(f.id)
+		}
+	}
+}
+
+// secretsFoundMsg holds an upload or save that waits for the user to decide what happens to the possible secrets
+type secretsFoundMsg struct {
+	uploadCheck
+	found []scannedFile
+}
+
+// localFiles returns the files of the gists with the content that is about to be uploaded,
+// published files without local changes are on github already and left out
+func (m *mainModel) localFiles(g *gist) ([]file, error) {
+	files := []file{}
+	for _, item := range m.gists[g] {
+		if f, ok := item.(file); ok && (f.draft || f.dirty) {
+			files = append(files, f)
+		}
+	}
+	return withPendingContent(files)
+}
+
+// scanGists scans the local content of the gists about to be uploaded
+func (m *mainModel) scanGists(gists []*gist) ([]scannedFile, error) {
+	var found []scannedFile
+	for _, g := range gists {
+		if !shouldScan(g.visiblity) {
+			continue
+		}
+		files, err := m.localFiles(g)
+		if err != nil {
+			return nil, err
+		}
+		scanned, err := scanFiles(g, files)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, scanned...)
+	}
+	return found, nil
+}
+
+func scanFailed(err error) []tea.Cmd {
+	log.Errorf("could not scan for secrets\n%v", err)
+	return []tea.Cmd{showInfo("could not scan for secrets, check the secretScan rules", info_error)}
+}
+
+// scanUpload holds the upload back when the gists hold possible secrets, and starts it otherwise
+func (m *model) scanUpload(check uploadCheck) []tea.Cmd {
+	found, err := m.mainScreen.scanGists(check.gists)
+	if err != nil {
+		return scanFailed(err)
+	}
+	if len(found) > 0 {
+		return []tea.Cmd{func() tea.Msg {
+			return secretsFoundMsg{uploadCheck: check, found: found}
+		}}
+	}
+	return m.startChecked(check)
+}
+
+// scanSave holds the save back when the content holds possible secrets, and saves it otherwise
+func (m *mainModel) scanSave(check uploadCheck) []tea.Cmd {
+	// drafts are only saved locally, they get scanned once they are uploaded
+	if !check.file.draft && shouldScan(check.gist.visiblity) {
+		f := check.file
+		f.content = check.content
+		found, err := scanFiles(check.gist, []file{f})
+		if err != nil {
+			return scanFailed(err)
+		}
+		if len(found) > 0 {
+			return []tea.Cmd{func() tea.Msg {
+				return secretsFoundMsg{uploadCheck: check, found: found}
+			}}
+		}
+	}
+	return m.saveChecked(check.gist, check.file, check.content)
+}
+
+// startChecked starts an upload that went through every check
+func (m *model) startChecked(check uploadCheck) []tea.Cmd {
+	if check.action == scan_upload_all {
+		return m.startUploads(check.gists)
+	}
+	upload, _ := m.uploadGist(check.gists[0], false)
+	return upload
+}
+
+func (m *model) secretsFound(msg secretsFoundMsg) tea.Cmd {
+	msg.release(m.mainScreen.syncs)
+	if m.dialogState == dialog_opened {
+		return showInfo(err_secrets_found.Error(), info_error)
+	}
+	m.mainScreen.pendingSecrets = &msg
+	// saving leaves the editor, where dialogs are disabled otherwise
+	m.dialogState = dialog_closed
+	return m.reInitDialog(msg, form_type_secrets)
+}
+
+// secretsReviewed carries on with the upload or save, with the possible secrets redacted when asked to
+func (m *model) secretsReviewed(redact bool) []tea.Cmd {
+	pending := m.mainScreen.pendingSecrets
+	m.mainScreen.pendingSecrets = nil
+	if pending == nil {
+		return nil
+	}
+
+	if pending.action == scan_save {
+		content := pending.content
+		if redact {
+			content = pending.found[0].redacted()
+			m.mainScreen.showSaved(pending.file.id, content)
+		}
+		return m.mainScreen.saveChecked(pending.gist, pending.file, content)
+	}
+
+	if pending.action == scan_visibility || pending.action == scan_transfer {
+		files := pending.files
+		if redact {
+			files = redactFiles(files, pending.found)
+		}
+		if pending.action == scan_visibility {
+			return m.recreateGist(pending.gist, pending.visibility, pending.deleteOriginal, files, true)
+		}
+		return m.sendTransfer(pending.gist, files, pending.target, pending.move, true)
+	}
+
+	var cmds []tea.Cmd
+	if redact {
+		for _, scanned := range pending.found {
+			cmds = append(cmds, m.mainScreen.keepPending(scanned.gist, scanned.file, scanned.redacted())...)
+		}
+	}
+	return append(cmds, m.startChecked(pending.uploadCheck)...)
+}
+
+// showSaved puts content that got rewritten on its way to github into the editor, when the file is open
+func (m *mainModel) showSaved(fileId, content string) {
+	if m.editorFile != fileId {
+		return
+	}
+	m.editor.SetContent(content)
+	m.syncPreview()
+}
+
+// keepPending keeps the content as the pending version of the file, so it is what gets uploaded
+func (m *mainModel) keepPending(g *gist, f file, content string) []tea.Cmd {
+	if err := storeBuffer(f.id, content, true); err != nil {
+		log.Errorf("could not keep the pending version of %q\n%v", f.title, err)
+		return []tea.Cmd{showInfo("could not keep the pending version", info_error)}
+	}
+	if m.editorFile == f.id {
+		m.editorStashed = content
+		m.showSaved(f.id, content)
+	}
+	items := m.gists[g]
+	if idx := fileIndex(items, f.id); idx >= 0 {
+		current, _ := items[idx].(file)
+		current.dirty = true
+		return m.replaceFile(g, current)
+	}
+	return nil
+}
+
+// renderSecrets lists the possible secrets for the dialog, cut to fit the height
+func renderSecrets(found []scannedFile, height int, styles DiffStyle) string {
+	lines := describeSecrets(found)
+	if height < 2 {
+		height = 2
+	}
+	if len(lines) > height-1 {
+		more := len(lines) - (height - 2)
+		lines = append(lines[:height-2], fmt.Sprintf("… %d more", more))
+	}
+	rendered := []string{styles.Warning.Render(fmt.Sprintf("Found %d possible secret(s)", countSecrets(found)))}
+	for _, line := range lines {
+		rendered = append(rendered, styles.Gutter.Render(line))
+	}
+	return lipgloss.NewStyle().Align(lipgloss.Left).Render(strings.Join(rendered, "\n"))
+}
@@ -21,6 +21,20 @@ type config struct {
 	AccessToken string `json:"access_token"`
 	ConfigPath  string `json:"configPath"`
 	Theme       string `json:"theme"`
+	// push changes to published gists through a local git mirror instead of the api
+	GitBackend bool `json:"gitBackend"`
+	// show a diff of the changes and ask for confirmation before saving a file
+	ReviewBeforeSave bool `json:"reviewBeforeSave"`
+	// seconds the editor has to be idle before its buffer is kept as a local draft, 0 uses the default and a negative value disables it
+	AutosaveDelay int `json:"autosaveDelay"`
+	// rules used to catch credentials before they get uploaded
+	SecretScan secretScanConfig `json:"secretScan"`
+	// rewrites applied to content before it gets uploaded
+	Transforms transformConfig `json:"transforms"`
+	// content new files of a language start with, keyed by language name or alias
+	Boilerplates map[string]string `json:"boilerplates"`
+	// copy through the terminal with OSC 52 even when it is not known to support it
+	OSC52 bool `json:"osc52"`
 }
 
 func (c *config) hasAccessToken() bool {
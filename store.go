@@ -16,12 +16,14 @@ type collectionName string
 const (
 	collectionGistContent  collectionName = "gist_content_list"
 	collectionDraftedGists collectionName = "drafted_gists"
+	collectionHttpCache    collectionName = "http_cache"
 )
 
 var (
 	collections = []collectionName{
 		collectionGistContent,
 		collectionDraftedGists,
+		collectionHttpCache,
 	}
 )
 
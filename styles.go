@@ -27,6 +27,15 @@ type DialogStyle struct {
 	BlurredButton    lipgloss.Style
 }
 
+type DiffStyle struct {
+	Header    lipgloss.Style
+	Warning   lipgloss.Style
+	Gutter    lipgloss.Style
+	Unchanged lipgloss.Style
+	Deleted   lipgloss.Style
+	Inserted  lipgloss.Style
+}
+
 type FilesBaseStyle struct {
 	Base               lipgloss.Style
 	Title              lipgloss.Style
@@ -35,6 +44,7 @@ type FilesBaseStyle struct {
 	UnselectedSubtitle lipgloss.Style
 	SelectedTitle      lipgloss.Style
 	UnselectedTitle    lipgloss.Style
+	Dirty              lipgloss.Style
 	NoItems            lipgloss.Style
 }
 
@@ -45,6 +55,8 @@ type GistsBaseStyle struct {
 	Selected   lipgloss.Style
 	Unselected lipgloss.Style
 	NoItems    lipgloss.Style
+	Public     lipgloss.Style
+	Secret     lipgloss.Style
 }
 
 type Styles struct {
@@ -52,6 +64,7 @@ type Styles struct {
 	Files     FilesStyle
 	Gists     GistsStyle
 	Dialog    DialogStyle
+	Diff      DiffStyle
 }
 
 func DefaultStyles(cfg *config) Styles {
@@ -92,6 +105,8 @@ func DefaultStyles(cfg *config) Styles {
 					UnsetBackground().
 					Foreground(gray).
 					Padding(0, 2),
+				Public: lipgloss.NewStyle().Foreground(lipgloss.Color("208")),
+				Secret: lipgloss.NewStyle().Foreground(gray),
 			},
 			Blurred: GistsBaseStyle{
 				Base:       lipgloss.NewStyle().Width(40).Height(1),
@@ -103,6 +118,8 @@ func DefaultStyles(cfg *config) Styles {
 					UnsetBackground().
 					Foreground(gray).
 					Padding(0, 2),
+				Public: lipgloss.NewStyle().Foreground(lipgloss.Color("208")),
+				Secret: lipgloss.NewStyle().Foreground(lipgloss.Color("237")),
 			},
 		},
 		Files: FilesStyle{
@@ -113,6 +130,7 @@ func DefaultStyles(cfg *config) Styles {
 				UnselectedSubtitle: lipgloss.NewStyle().Foreground(lipgloss.Color("237")),
 				SelectedTitle:      lipgloss.NewStyle().Foreground(primary),
 				UnselectedTitle:    lipgloss.NewStyle().Foreground(gray),
+				Dirty:              lipgloss.NewStyle().Foreground(lipgloss.Color("208")),
 				NoItems: lipgloss.NewStyle().
 					UnsetBackground().
 					Foreground(gray).
@@ -125,6 +143,7 @@ func DefaultStyles(cfg *config) Styles {
 				UnselectedSubtitle: lipgloss.NewStyle().Foreground(black),
 				SelectedTitle:      lipgloss.NewStyle().Foreground(primary),
 				UnselectedTitle:    lipgloss.NewStyle().Foreground(lipgloss.Color("237")),
+				Dirty:              lipgloss.NewStyle().Foreground(lipgloss.Color("208")),
 				NoItems: lipgloss.NewStyle().
 					UnsetBackground().
 					Foreground(gray).
@@ -141,5 +160,13 @@ func DefaultStyles(cfg *config) Styles {
 			FocusedButton:    lipgloss.NewStyle().Padding(0, 2).MarginRight(1).Foreground(lipgloss.Color("0")).Background(secondary),
 			BlurredButton:    lipgloss.NewStyle().Padding(0, 2).MarginRight(1).Foreground(lipgloss.Color("7")).Background(lipgloss.Color("0")),
 		},
+		Diff: DiffStyle{
+			Header:    lipgloss.NewStyle().Foreground(white).Bold(true),
+			Warning:   lipgloss.NewStyle().Foreground(lipgloss.Color("208")).Bold(true),
+			Gutter:    lipgloss.NewStyle().Foreground(gray),
+			Unchanged: lipgloss.NewStyle().Foreground(gray),
+			Deleted:   lipgloss.NewStyle().Foreground(lipgloss.Color("203")),
+			Inserted:  lipgloss.NewStyle().Foreground(lipgloss.Color("114")),
+		},
 	}
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// syncTracker keeps track of the gists and files that have a request in flight, so the list
+// delegates can render a spinner next to them and the user can cancel the requests
+type syncTracker struct {
+	inflight map[string]context.CancelFunc
+	spinner  spinner.Model
+	ticking  bool
+}
+
+func newSyncTracker() *syncTracker {
+	return &syncTracker{
+		inflight: map[string]context.CancelFunc{},
+		spinner:  spinner.New(spinner.WithSpinner(spinner.MiniDot)),
+	}
+}
+
+// begin marks the item as syncing and returns the context its request should use, ok is false
+// when the item already has a request in flight
+func (s *syncTracker) begin(id string) (ctx context.Context, tick tea.Cmd, ok bool) {
+	if _, exists := s.inflight[id]; exists {
+		return nil, nil, false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.inflight[id] = cancel
+
+	// only kick the spinner off once, it keeps ticking until everything is done
+	if !s.ticking {
+		s.ticking = true
+		tick = s.spinner.Tick
+	}
+	return ctx, tick, true
+}
+
+// beginAll marks several items as syncing under a single request, ok is false when any of them is busy already
+func (s *syncTracker) beginAll(ids []string) (ctx context.Context, tick tea.Cmd, ok bool) {
+	if len(ids) == 0 {
+		return nil, nil, false
+	}
+	for _, id := range ids {
+		if s.isSyncing(id) {
+			return nil, nil, false
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.inflight[ids[0]] = cancel
+	for _, id := range ids[1:] {
+		s.inflight[id] = cancel
+	}
+
+	if !s.ticking {
+		s.ticking = true
+		tick = s.spinner.Tick
+	}
+	return ctx, tick, true
+}
+
+func (s *syncTracker) done(id string) {
+	if cancel, ok := s.inflight[id]; ok {
+		cancel()
+		delete(s.inflight, id)
+	}
+}
+
+func (s *syncTracker) isSyncing(id string) bool {
+	_, ok := s.inflight[id]
+	return ok
+}
+
+func (s *syncTracker) cancelAll() int {
+	for _, cancel := range s.inflight {
+		cancel()
+	}
+	return len(s.inflight)
+}
+
+func (s *syncTracker) badge() string {
+	return s.spinner.View()
+}
+
+func (s *syncTracker) update(msg spinner.TickMsg) tea.Cmd {
+	if msg.ID != s.spinner.ID() {
+		return nil
+	}
+	var cmd tea.Cmd
+	s.spinner, cmd = s.spinner.Update(msg)
+	if len(s.inflight) == 0 {
+		s.ticking = false
+		return nil
+	}
+	return cmd
+}
+
+// syncFailed reports a failed request, cancelled requests are not treated as errors
+func syncFailed(info string, err error) []tea.Cmd {
+	if errors.Is(err, context.Canceled) {
+		return []tea.Cmd{showInfo("request cancelled", info_default)}
+	}
+	log.Errorf("%s\n%v", info, err)
+	return []tea.Cmd{showInfo(info, info_error)}
+}
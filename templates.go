@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/ostafen/clover/v2/document"
+)
+
+// optional file of a template describing its variables
+const templateManifest = "template.json"
+
+// variables every template gets without asking for them
+const (
+	template_var_gist = "gist"
+	template_var_date = "date"
+)
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+type templateVariable struct {
+	Name    string `json:"name"`
+	Prompt  string `json:"prompt"`
+	Default string `json:"default"`
+}
+
+func (v templateVariable) title() string {
+	if v.Prompt != "" {
+		return v.Prompt
+	}
+	return v.Name
+}
+
+type templateFile struct {
+	name    string
+	content string
+}
+
+// gistTemplate is a directory under ConfigPath/templates, every file in it becomes a file of the gist
+type gistTemplate struct {
+	name        string
+	Description string             `json:"description"`
+	Variables   []templateVariable `json:"variables"`
+	files       []templateFile
+}
+
+func templatesDir() string {
+	return filepath.Join(cfg.ConfigPath, "templates")
+}
+
+// listTemplates returns the names of the templates sorted alphabetically
+func listTemplates() ([]string, error) {
+	entries, err := os.ReadDir(templatesDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func loadTemplate(name string) (*gistTemplate, error) {
+	if name == "" || filepath.Base(name) != name {
+		return nil, fmt.Errorf("invalid template name %q", name)
+	}
+	dir := filepath.Join(templatesDir(), name)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("template %q not found in %s", name, templatesDir())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := &gistTemplate{name: name}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		// gists are flat, nested directories can't be part of one
+		if entry.IsDir() {
+			log.Warnf("skipping directory %q of template %q", entry.Name(), name)
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Name() == templateManifest {
+			if err := json.Unmarshal(data, t); err != nil {
+				return nil, fmt.Errorf("invalid %s of template %q: %w", templateManifest, name, err)
+			}
+			continue
+		}
+		t.files = append(t.files, templateFile{name: entry.Name(), content: string(data)})
+	}
+	if len(t.files) == 0 {
+		return nil, fmt.Errorf("template %q has no files", name)
+	}
+
+	// placeholders that are not described in the manifest are asked for as they are
+	for _, f := range t.files {
+		for _, text := range []string{f.name, f.content} {
+			for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+				if !t.hasVariable(match[1]) {
+					t.Variables = append(t.Variables, templateVariable{Name: match[1]})
+				}
+			}
+		}
+	}
+	return t, nil
+}
+
+func (t *gistTemplate) hasVariable(name string) bool {
+	if name == template_var_gist || name == template_var_date {
+		return true
+	}
+	return slices.ContainsFunc(t.Variables, func(v templateVariable) bool { return v.Name == name })
+}
+
+// render fills the placeholders in, variables without a value fall back to their default
+func (t *gistTemplate) render(gistName string, values map[string]string) ([]file, error) {
+	vars := map[string]string{
+		template_var_gist: gistName,
+		template_var_date: time.Now().Format(time.DateOnly),
+	}
+	for _, v := range t.Variables {
+		vars[v.Name] = v.Default
+		if value, ok := values[v.Name]; ok && value != "" {
+			vars[v.Name] = value
+		}
+	}
+	fill := func(text string) string {
+		return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+			return vars[placeholderPattern.FindStringSubmatch(placeholder)[1]]
+		})
+	}
+
+	files := []file{}
+	seen := map[string]bool{}
+	for _, f := range t.files {
+		title := strings.TrimSpace(fill(f.name))
+		if title == "" || strings.ContainsAny(title, `/\`) {
+			return nil, fmt.Errorf("template file %q renders to the invalid filename %q", f.name, title)
+		}
+		if seen[title] {
+			return nil, fmt.Errorf("template renders %q more than once", title)
+		}
+		seen[title] = true
+		files = append(files, file{title: title, content: fill(f.content)})
+	}
+	return files, nil
+}
+
+// promptTemplate asks for the variables that were not passed on the command line, an empty answer keeps the default
+func promptTemplate(reader *bufio.Reader, t *gistTemplate, values map[string]string) error {
+	for _, v := range t.Variables {
+		if _, ok := values[v.Name]; ok {
+			continue
+		}
+		if v.Default != "" {
+			fmt.Printf("%s [%s]: ", v.title(), v.Default)
+		} else {
+			fmt.Printf("%s: ", v.title())
+		}
+		answer, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		values[v.Name] = strings.TrimSpace(answer)
+		if errors.Is(err, io.EOF) {
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+// parseTemplateVars turns name=value pairs into template values
+func parseTemplateVars(pairs []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid template variable %q, expected name=value", pair)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// templateChosenMsg holds a gist created from a template until its variables are filled in
+type templateChosenMsg struct {
+	name       string
+	visibility gistVisibility
+	template   *gistTemplate
+}
+
+func (m *model) chooseTemplate(name string, visibility gistVisibility, templateName string) []tea.Cmd {
+	t, err := loadTemplate(templateName)
+	if err != nil {
+		log.Errorf("could not load template %q\n%v", templateName, err)
+		return []tea.Cmd{showInfo("could not load template", info_error)}
+	}
+	if len(t.Variables) == 0 {
+		return m.createFromTemplate(name, visibility, t, nil)
+	}
+	// the create dialog is still open, the variables get asked for once it closed
+	return []tea.Cmd{func() tea.Msg {
+		return templateChosenMsg{name: name, visibility: visibility, template: t}
+	}}
+}
+
+func (m *model) templateChosen(msg templateChosenMsg) tea.Cmd {
+	m.mainScreen.pendingTemplate = &msg
+	m.dialogState = dialog_closed
+	return m.reInitDialog(msg, form_type_template)
+}
+
+// createFromTemplate creates a drafted gist holding the rendered files of the template
+func (m *model) createFromTemplate(name string, visibility gistVisibility, t *gistTemplate, values map[string]string) []tea.Cmd {
+	if name == "" {
+		name = time.Now().String()
+	}
+	files, err := t.render(name, values)
+	if err != nil {
+		log.Errorf("could not render template %q\n%v", t.name, err)
+		return []tea.Cmd{showInfo("could not render template", info_error)}
+	}
+
+	g, cmds := m.createGist(name, visibility)
+	if g == nil {
+		return cmds
+	}
+
+	updatedAt := time.Now().In(time.Local).String()
+	items := []list.Item{}
+	for _, f := range files {
+		f.id = uuid.New().String()
+		f.gistId = g.id
+		f.updatedAt = updatedAt
+		f.draft = true
+		f.size = len(f.content)
+
+		doc := document.NewDocument()
+		doc.SetAll(map[string]any{
+			"id":        f.id,
+			"title":     f.title,
+			"desc":      f.desc,
+			"gistId":    f.gistId,
+			"content":   f.content,
+			"rawUrl":    "",
+			"dirty":     false,
+			"updatedAt": f.updatedAt,
+			"draft":     true,
+		})
+		if err := storage.db.Insert(string(collectionGistContent), doc); err != nil {
+			log.Errorf("could not insert template file %q\n%v", f.title, err)
+			return append(cmds, showInfo("could not insert into db", info_error))
+		}
+		items = append(items, f)
+	}
+
+	m.mainScreen.gists[g] = items
+	cmds = append(cmds, m.mainScreen.refreshFileList(g)...)
+	return append(cmds, showInfo(fmt.Sprintf("new gist created from %s", t.name), info_default))
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateRender(t *testing.T) {
+	today := time.Now().Format(time.DateOnly)
+	tests := []struct {
+		name      string
+		variables []templateVariable
+		files     []templateFile
+		values    map[string]string
+		// title and content of every rendered file, joined by a colon
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "builtin variables",
+			files: []templateFile{{name: "{{gist}}.md", content: "# {{ gist }}\n{{date}}"}},
+			want:  []string{"notes.md:# notes\n" + today},
+		},
+		{
+			name:      "value wins over default",
+			variables: []templateVariable{{Name: "lang", Default: "go"}},
+			files:     []templateFile{{name: "main.{{lang}}", content: "lang={{lang}}"}},
+			values:    map[string]string{"lang": "rs"},
+			want:      []string{"main.rs:lang=rs"},
+		},
+		{
+			name:      "empty value keeps the default",
+			variables: []templateVariable{{Name: "lang", Default: "go"}},
+			files:     []templateFile{{name: "main.{{lang}}", content: ""}},
+			values:    map[string]string{"lang": ""},
+			want:      []string{"main.go:"},
+		},
+		{
+			name:  "unknown placeholder renders empty",
+			files: []templateFile{{name: "a.txt", content: "[{{missing}}]"}},
+			want:  []string{"a.txt:[]"},
+		},
+		{
+			name:  "text that only looks like a placeholder",
+			files: []templateFile{{name: "a.txt", content: "{{ 1 }} {x}"}},
+			want:  []string{"a.txt:{{ 1 }} {x}"},
+		},
+		{
+			name:      "empty filename",
+			variables: []templateVariable{{Name: "name"}},
+			files:     []templateFile{{name: "{{name}}", content: ""}},
+			wantErr:   true,
+		},
+		{
+			name:      "filename with a slash",
+			variables: []templateVariable{{Name: "name"}},
+			files:     []templateFile{{name: "{{name}}.txt", content: ""}},
+			values:    map[string]string{"name": "../etc"},
+			wantErr:   true,
+		},
+		{
+			name:      "duplicate filenames",
+			variables: []templateVariable{{Name: "a"}, {Name: "b"}},
+			files:     []templateFile{{name: "{{a}}.txt"}, {name: "{{b}}.txt"}},
+			values:    map[string]string{"a": "same", "b": "same"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := &gistTemplate{name: "test", Variables: tt.variables, files: tt.files}
+			files, err := tmpl.render("notes", tt.values)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("render = %v, want an error", files)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, f := range files {
+				got = append(got, f.title+":"+f.content)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("render = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	dir := filepath.Join(templatesDir(), "snippet")
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(templatesDir()) })
+	for name, content := range map[string]string{
+		templateManifest: `{"description": "a snippet", "variables": [{"name": "lang", "prompt": "Language", "default": "go"}]}`,
+		"main.{{lang}}":  "// {{ author }} on {{date}}",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tmpl, err := loadTemplate("snippet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Description != "a snippet" {
+		t.Errorf("description = %q, want %q", tmpl.Description, "a snippet")
+	}
+	// the manifest and the nested directory are not files of the gist
+	if len(tmpl.files) != 1 || tmpl.files[0].name != "main.{{lang}}" {
+		t.Errorf("files = %+v, want only main.{{lang}}", tmpl.files)
+	}
+	var names []string
+	for _, v := range tmpl.Variables {
+		names = append(names, v.Name)
+	}
+	if want := []string{"lang", "author"}; !slices.Equal(names, want) {
+		t.Errorf("variables = %q, want %q", names, want)
+	}
+
+	for _, name := range []string{"", "../snippet", "missing"} {
+		if _, err := loadTemplate(name); err == nil {
+			t.Errorf("loadTemplate(%q) succeeded, want an error", name)
+		}
+	}
+}
+
+func TestParseTemplateVars(t *testing.T) {
+	values, err := parseTemplateVars([]string{"lang=go", "title=a=b", "empty="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"lang": "go", "title": "a=b", "empty": ""}
+	for name, value := range want {
+		if values[name] != value {
+			t.Errorf("%s = %q, want %q", name, values[name], value)
+		}
+	}
+	for _, pair := range []string{"lang", "=go"} {
+		if _, err := parseTemplateVars([]string{pair}); err == nil {
+			t.Errorf("parseTemplateVars(%q) succeeded, want an error", pair)
+		}
+	}
+}
+
+func TestPromptsShareInput(t *testing.T) {
+	// piped answers for several prompts in a row, each prompt must only take its own line
+	reader := bufio.NewReader(strings.NewReader("rs\nalice\nr\n"))
+
+	first := &gistTemplate{Variables: []templateVariable{{Name: "lang"}}}
+	second := &gistTemplate{Variables: []templateVariable{{Name: "author"}, {Name: "skipped"}}}
+	values := map[string]string{"skipped": "given"}
+	if err := promptTemplate(reader, first, values); err != nil {
+		t.Fatal(err)
+	}
+	if err := promptTemplate(reader, second, values); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"lang": "rs", "author": "alice", "skipped": "given"}
+	for name, value := range want {
+		if values[name] != value {
+			t.Errorf("%s = %q, want %q", name, values[name], value)
+		}
+	}
+
+	redact, err := promptSecrets(reader, nil)
+	if err != nil || !redact {
+		t.Errorf("promptSecrets = %v, %v, want the redact answer", redact, err)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/go-github/v74/github"
+	"github.com/google/uuid"
+	"github.com/ostafen/clover/v2/document"
+	"github.com/ostafen/clover/v2/query"
+)
+
+type filesTransferredMsg struct {
+	source *gist
+	target *gist
+	// files as they were in the source, holding the content that got transferred
+	files []file
+	move  bool
+	// the target gist after the files were added, nil when it is a draft
+	response *github.Gist
+	err      error
+	// the files made it into the target but could not be removed from the source
+	removeErr error
+}
+
+// transferFiles copies the files into the target gist, moving them removes them from the source afterwards
+func (m *model) transferFiles(source *gist, files []file, target *gist, move bool) []tea.Cmd {
+	if source == target {
+		return []tea.Cmd{showInfo("files are already in this gist", info_default)}
+	}
+	for _, item := range m.mainScreen.gists[target] {
+		existing, _ := item.(file)
+		for _, f := range files {
+			if existing.title == f.title {
+				return []tea.Cmd{showInfo(fmt.Sprintf("%q already exists in %s", f.title, target.name), info_error)}
+			}
+		}
+	}
+
+	return m.sendTransfer(source, files, target, move, false)
+}
+
+// sendTransfer copies or moves the files. the content gets loaded and scanned for secrets first, unless
+// the files were reviewed already and hold what has to be sent
+func (m *model) sendTransfer(source *gist, files []file, target *gist, move bool, reviewed bool) []tea.Cmd {
+	ids := []string{target.id}
+	for _, f := range files {
+		ids = append(ids, f.id)
+	}
+	ctx, tick, ok := m.mainScreen.syncs.beginAll(ids)
+	if !ok {
+		return []tea.Cmd{showInfo("file is still syncing", info_default)}
+	}
+
+	client := m.client
+	msg := filesTransferredMsg{source: source, target: target, files: files, move: move}
+	targetDraft := target.status == gist_status_drafted
+	sourceDraft := source.status == gist_status_drafted
+	sourceId, targetId := source.id, target.id
+
+	return []tea.Cmd{tick, func() tea.Msg {
+		pending := files
+		if !reviewed {
+			var err error
+			if pending, err = fullContent(files); err != nil {
+				msg.err = err
+				return msg
+			}
+			// drafts keep the content locally, it gets scanned once they are uploaded
+			if !targetDraft {
+				if found, err := scanTransfer(target, target.visiblity, pending); err != nil || len(found) > 0 {
+					msg.err = err
+					if len(found) > 0 {
+						return secretsFoundMsg{uploadCheck: uploadCheck{
+							action: scan_transfer, gist: source, files: pending, target: target, move: move,
+						}, found: found}
+					}
+					return msg
+				}
+			}
+		}
+		msg.files = pending
+
+		if !targetDraft {
+			binary := false
+			change := gitChange{write: map[string][]byte{}, message: fmt.Sprintf("Add %s", joinTitles(pending))}
+			for _, f := range pending {
+				change.write[f.title] = []byte(f.content)
+				binary = binary || isBinary([]byte(f.content))
+			}
+			// json would mangle binary content, those can only go through git
+			if cfg.GitBackend || binary {
+				msg.response, msg.err = pushGistChange(ctx, client, targetId, change)
+			} else {
+				msg.response, _, msg.err = client.Gists.Edit(ctx, targetId, &github.Gist{Files: filesPayload(pending)})
+			}
+			if msg.err != nil {
+				return msg
+			}
+		}
+
+		if move && !sourceDraft {
+			// the source is the only complete copy until the target is known to hold all of it
+			if msg.removeErr = arrivedComplete(msg.response, pending); msg.removeErr != nil {
+				return msg
+			}
+			removed := []string{}
+			payload := github.Gist{Files: map[github.GistFilename]github.GistFile{}}
+			for _, f := range pending {
+				removed = append(removed, f.title)
+				payload.Files[github.GistFilename(f.title)] = github.GistFile{}
+			}
+			if cfg.GitBackend {
+				msg.removeErr = pushChange(ctx, sourceId, gitChange{
+					remove:  removed,
+					message: fmt.Sprintf("Delete %s", strings.Join(removed, ", ")),
+				})
+			} else {
+				_, _, msg.removeErr = client.Gists.Edit(ctx, sourceId, &payload)
+			}
+		}
+		return msg
+	}}
+}
+
+// arrivedComplete checks that the target gist holds every file at its full size, drafts keep the
+// content locally so there is nothing to check
+func arrivedComplete(target *github.Gist, files []file) error {
+	if target == nil {
+		return nil
+	}
+	for _, f := range files {
+		got, ok := target.GetFiles()[github.GistFilename(f.title)]
+		if !ok || got.GetSize() != len(f.content) {
+			return fmt.Errorf("%q did not fully arrive in the target, it was kept in the source", f.title)
+		}
+	}
+	return nil
+}
+
+func joinTitles(files []file) string {
+	titles := make([]string, len(files))
+	for i, f := range files {
+		titles[i] = f.title
+	}
+	return strings.Join(titles, ", ")
+}
+
+func (m *model) filesTransferred(msg filesTransferredMsg) []tea.Cmd {
+	var cmds []tea.Cmd
+	source, target := msg.source, msg.target
+
+	m.mainScreen.syncs.done(target.id)
+	for _, f := range msg.files {
+		m.mainScreen.syncs.done(f.id)
+	}
+	action := "copy"
+	if msg.move {
+		action = "move"
+	}
+	if errors.Is(msg.err, err_truncated) {
+		return []tea.Cmd{showInfo(fmt.Sprintf("could not %s %v", action, msg.err), info_error)}
+	}
+	if msg.err != nil {
+		return syncFailed(fmt.Sprintf("could not %s files to %s", action, target.name), msg.err)
+	}
+
+	updatedAt := time.Now().In(time.Local)
+	respFiles := map[github.GistFilename]github.GistFile{}
+	if msg.response != nil {
+		updatedAt = msg.response.GetUpdatedAt().In(time.Local)
+		respFiles = msg.response.GetFiles()
+	}
+	// moving only keeps the stored document when the file is actually gone from the source
+	reuse := msg.move && msg.removeErr == nil
+
+	added := []list.Item{}
+	for _, f := range msg.files {
+		respFile := respFiles[github.GistFilename(f.title)]
+		moved := file{
+			id:        f.id,
+			gistId:    target.id,
+			title:     f.title,
+			desc:      target.name,
+			rawUrl:    respFile.GetRawURL(),
+			updatedAt: updatedAt.String(),
+			content:   f.content,
+			draft:     target.status == gist_status_drafted,
+			size:      len(f.content),
+			language:  f.language,
+		}
+
+		if reuse {
+			q := query.NewQuery(string(collectionGistContent)).Where(query.Field("id").Eq(f.id))
+			err := storage.db.Update(q, map[string]any{
+				"gistId":    moved.gistId,
+				"desc":      moved.desc,
+				"rawUrl":    moved.rawUrl,
+				"updatedAt": moved.updatedAt,
+				"content":   moved.content,
+				"draft":     moved.draft,
+				"dirty":     false,
+				"buffer":    "",
+			})
+			if err != nil {
+				log.Errorf("could not move %q in the collection\n%v", f.title, err)
+				return append(cmds, showInfo("could not update the collection", info_error))
+			}
+			cmds = append(cmds, m.mainScreen.removeFile(source, f.id)...)
+		} else {
+			moved.id = uuid.New().String()
+			doc := document.NewDocument()
+			doc.SetAll(map[string]any{
+				"id":        moved.id,
+				"title":     moved.title,
+				"desc":      moved.desc,
+				"gistId":    moved.gistId,
+				"content":   moved.content,
+				"rawUrl":    moved.rawUrl,
+				"dirty":     false,
+				"updatedAt": moved.updatedAt,
+				"draft":     moved.draft,
+				"language":  moved.language,
+			})
+			if err := storage.db.Insert(string(collectionGistContent), doc); err != nil {
+				log.Errorf("could not copy %q in the collection\n%v", f.title, err)
+				return append(cmds, showInfo("could not insert into db", info_error))
+			}
+		}
+		added = append(added, moved)
+	}
+
+	m.mainScreen.gists[target] = append(m.mainScreen.gists[target], added...)
+	if msg.response != nil {
+		target.updatedAt = updatedAt
+	}
+	m.mainScreen.marks.clearFiles()
+	cmds = append(cmds, m.mainScreen.refreshFileList(target)...)
+
+	if msg.removeErr != nil {
+		return append(cmds, syncFailed(fmt.Sprintf("copied to %s but could not remove the files from %s", target.name, source.name), msg.removeErr)...)
+	}
+	past := "copied"
+	if msg.move {
+		past = "moved"
+	}
+	return append(cmds, showInfo(fmt.Sprintf("%s %d file(s) to %s", past, len(msg.files), target.name), info_default))
+}
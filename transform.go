@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var err_transform_cancelled = errors.New("cancelled, nothing was uploaded")
+
+// transformRule replaces whatever the pattern matches, the replacement can refer to groups like $1
+type transformRule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+type transformConfig struct {
+	// built-in transforms to apply, in the order they are listed
+	Enabled []string `json:"enabled"`
+	// replacements applied after the built-in transforms
+	Replace []transformRule `json:"replace"`
+}
+
+const (
+	transform_home                = "home"
+	transform_hostname            = "hostname"
+	transform_email               = "email"
+	transform_trailing_whitespace = "trailing-whitespace"
+	transform_line_endings        = "line-endings"
+)
+
+var (
+	emailPattern              = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	trailingWhitespacePattern = regexp.MustCompile(`[ \t]+(\r?\n|\z)`)
+)
+
+type transformStep func(string) string
+
+type transformer struct {
+	steps []transformStep
+}
+
+// replaceLiteral replaces every occurrence of old, as long as it is not part of a longer word
+func replaceLiteral(old, placeholder string) transformStep {
+	pattern := regexp.MustCompile(`(^|[^\w.\-])` + regexp.QuoteMeta(old) + `\b`)
+	return func(content string) string {
+		return pattern.ReplaceAllString(content, "${1}"+placeholder)
+	}
+}
+
+func newTransformer(c transformConfig) (*transformer, error) {
+	t := &transformer{}
+	for _, name := range c.Enabled {
+		switch name {
+		case transform_home:
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			t.steps = append(t.steps, replaceLiteral(home, "~"))
+		case transform_hostname:
+			hostname, err := os.Hostname()
+			if err != nil {
+				return nil, err
+			}
+			t.steps = append(t.steps, replaceLiteral(hostname, "<hostname>"))
+		case transform_email:
+			t.steps = append(t.steps, func(content string) string {
+				return emailPattern.ReplaceAllString(content, "<email>")
+			})
+		case transform_trailing_whitespace:
+			t.steps = append(t.steps, func(content string) string {
+				return trailingWhitespacePattern.ReplaceAllString(content, "$1")
+			})
+		case transform_line_endings:
+			t.steps = append(t.steps, func(content string) string {
+				return strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\r", "\n")
+			})
+		default:
+			return nil, fmt.Errorf("unknown transform %q", name)
+		}
+	}
+	for _, rule := range c.Replace {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transform pattern %q: %w", rule.Pattern, err)
+		}
+		replacement := rule.Replacement
+		t.steps = append(t.steps, func(content string) string {
+			return pattern.ReplaceAllString(content, replacement)
+		})
+	}
+	return t, nil
+}
+
+func (t *transformer) apply(content string) string {
+	if isBinary([]byte(content)) {
+		return content
+	}
+	for _, step := range t.steps {
+		content = step(content)
+	}
+	return content
+}
+
+// transformedFile is a file the transforms changed
+type transformedFile struct {
+	gist        *gist
+	file        file
+	original    string
+	transformed string
+}
+
+// transformFiles runs the transforms over the files, only the files that changed are returned
+func transformFiles(g *gist, files []file) ([]transformedFile, error) {
+	if len(cfg.Transforms.Enabled) == 0 && len(cfg.Transforms.Replace) == 0 {
+		return nil, nil
+	}
+	t, err := newTransformer(cfg.Transforms)
+	if err != nil {
+		return nil, err
+	}
+	var changed []transformedFile
+	for _, f := range files {
+		if transformed := t.apply(f.content); transformed != f.content {
+			changed = append(changed, transformedFile{gist: g, file: f, original: f.content, transformed: transformed})
+		}
+	}
+	return changed, nil
+}
+
+// visibleWhitespace shows carriage returns and trailing whitespace, so changes to them show up in a diff
+func visibleWhitespace(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		line = strings.ReplaceAll(line, "\r", "␍")
+		trimmed := strings.TrimRight(line, " \t")
+		lines[i] = trimmed + strings.Repeat("·", len(line)-len(trimmed))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func diffTransformed(f transformedFile) []diffLine {
+	return diffLines(visibleWhitespace(f.original), visibleWhitespace(f.transformed))
+}
+
+// transformsMsg holds an upload or save that waits for the user to look over the transformed content
+type transformsMsg struct {
+	uploadCheck
+	changed []transformedFile
+}
+
+func transformFailed(err error) []tea.Cmd {
+	log.Errorf("could not transform the content\n%v", err)
+	return []tea.Cmd{showInfo("could not transform the content, check the transforms config", info_error)}
+}
+
+// transformUpload holds the upload back when the transforms change any file, and scans it otherwise
+func (m *model) transformUpload(check uploadCheck) []tea.Cmd {
+	var changed []transformedFile
+	for _, g := range check.gists {
+		files, err := m.mainScreen.localFiles(g)
+		if err != nil {
+			return transformFailed(err)
+		}
+		transformed, err := transformFiles(g, files)
+		if err != nil {
+			return transformFailed(err)
+		}
+		changed = append(changed, transformed...)
+	}
+	if len(changed) > 0 {
+		return []tea.Cmd{func() tea.Msg {
+			return transformsMsg{uploadCheck: check, changed: changed}
+		}}
+	}
+	return m.scanUpload(check)
+}
+
+// transformSave holds the save back when the transforms change the content, and scans it otherwise
+func (m *mainModel) transformSave(check uploadCheck) []tea.Cmd {
+	// drafts are only saved locally, they get transformed once they are uploaded
+	if !check.file.draft {
+		f := check.file
+		f.content = check.content
+		changed, err := transformFiles(check.gist, []file{f})
+		if err != nil {
+			return transformFailed(err)
+		}
+		if len(changed) > 0 {
+			return []tea.Cmd{func() tea.Msg {
+				return transformsMsg{uploadCheck: check, changed: changed}
+			}}
+		}
+	}
+	return m.scanSave(check)
+}
+
+func (m *model) transformsFound(msg transformsMsg) tea.Cmd {
+	if m.dialogState == dialog_opened {
+		return showInfo(err_transform_cancelled.Error(), info_error)
+	}
+	m.mainScreen.pendingTransforms = &msg
+	// saving leaves the editor, where dialogs are disabled otherwise
+	m.dialogState = dialog_closed
+	return m.reInitDialog(msg, form_type_transforms)
+}
+
+// transformsReviewed carries on with the upload or save, with the transformed content when asked to
+func (m *model) transformsReviewed(apply bool) []tea.Cmd {
+	pending := m.mainScreen.pendingTransforms
+	m.mainScreen.pendingTransforms = nil
+	if pending == nil {
+		return nil
+	}
+
+	check := pending.uploadCheck
+	if check.action == scan_save {
+		if apply {
+			check.content = pending.changed[0].transformed
+			m.mainScreen.showSaved(check.file.id, check.content)
+		}
+		return m.mainScreen.scanSave(check)
+	}
+
+	var cmds []tea.Cmd
+	if apply {
+		for _, f := range pending.changed {
+			cmds = append(cmds, m.mainScreen.keepPending(f.gist, f.file, f.transformed)...)
+		}
+	}
+	return append(cmds, m.scanUpload(check)...)
+}
+
+// renderTransforms shows what the transforms changed in every file, sharing the height between them
+func renderTransforms(changed []transformedFile, width, height int, styles DiffStyle) string {
+	shown := changed
+	if maxFiles := max(height/4, 1); len(shown) > maxFiles {
+		shown = shown[:maxFiles]
+	}
+	budget := height/len(shown) - 1
+
+	sections := []string{}
+	for _, f := range shown {
+		sections = append(sections,
+			styles.Header.Render(f.file.title),
+			renderSideBySide(diffTransformed(f), width, budget, styles),
+		)
+	}
+	if more := len(changed) - len(shown); more > 0 {
+		sections = append(sections, styles.Gutter.Render(fmt.Sprintf("… %d more file(s)", more)))
+	}
+	return lipgloss.NewStyle().Align(lipgloss.Left).Render(strings.Join(sections, "\n"))
+}
+
+// promptTransforms shows what the transforms changed on the command line,
+// it reports whether the transformed content is used and fails when the upload is cancelled
+func promptTransforms(reader *bufio.Reader, changed []transformedFile) (bool, error) {
+	for _, f := range changed {
+		fmt.Printf("--- %s\n", f.file.title)
+		for _, line := range diffTransformed(f) {
+			switch line.op {
+			case diff_delete:
+				fmt.Println("- " + line.text)
+			case diff_insert:
+				fmt.Println("+ " + line.text)
+			}
+		}
+	}
+
+	for {
+		fmt.Print("[a]pply the changes, [s]kip them or [c]ancel? ")
+		answer, err := reader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "a", "apply":
+			return true, nil
+		case "s", "skip":
+			return false, nil
+		case "c", "cancel":
+			return false, err_transform_cancelled
+		}
+		if err != nil {
+			fmt.Println()
+			return false, err_transform_cancelled
+		}
+	}
+}
+
+// confirmTransforms transforms the files headed to a gist from the command line, once the changes are confirmed
+func confirmTransforms(files []file) ([]file, error) {
+	changed, err := transformFiles(nil, files)
+	if err != nil || len(changed) == 0 {
+		return files, err
+	}
+	apply, err := promptTransforms(stdin, changed)
+	if err != nil || !apply {
+		return files, err
+	}
+
+	transformed := slices.Clone(files)
+	for _, f := range changed {
+		for i := range transformed {
+			if transformed[i].id == f.file.id && transformed[i].title == f.file.title {
+				transformed[i].content = f.transformed
+			}
+		}
+	}
+	return transformed, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTransformer(t *testing.T) {
+	t.Setenv("HOME", "/home/alice")
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  transformConfig
+		content string
+		want    string
+	}{
+		{name: "nothing enabled", content: "/home/alice/bin \r\n", want: "/home/alice/bin \r\n"},
+		{
+			name:    "home",
+			config:  transformConfig{Enabled: []string{transform_home}},
+			content: "cd /home/alice/src && ls /home/alice",
+			want:    "cd ~/src && ls ~",
+		},
+		{
+			name:    "home inside a longer path",
+			config:  transformConfig{Enabled: []string{transform_home}},
+			content: "/srv/home/alice /home/alicex",
+			want:    "/srv/home/alice /home/alicex",
+		},
+		{
+			name:    "hostname",
+			config:  transformConfig{Enabled: []string{transform_hostname}},
+			content: "ssh " + hostname + "\nuser@" + hostname + ":~$",
+			want:    "ssh <hostname>\nuser@<hostname>:~$",
+		},
+		{
+			name:    "email",
+			config:  transformConfig{Enabled: []string{transform_email}},
+			content: "Author: Alice <alice.smith+gists@example.co.uk>",
+			want:    "Author: Alice <<email>>",
+		},
+		{
+			name:    "trailing whitespace",
+			config:  transformConfig{Enabled: []string{transform_trailing_whitespace}},
+			content: "a  \nb\t\r\nc \t",
+			want:    "a\nb\r\nc",
+		},
+		{
+			name:    "line endings",
+			config:  transformConfig{Enabled: []string{transform_line_endings}},
+			content: "a\r\nb\rc\n",
+			want:    "a\nb\nc\n",
+		},
+		{
+			name:    "replace with groups",
+			config:  transformConfig{Replace: []transformRule{{Pattern: `acme-(\w+)\.internal`, Replacement: "example-$1.com"}}},
+			content: "curl https://acme-api.internal/v1",
+			want:    "curl https://example-api.com/v1",
+		},
+		{
+			name: "replacements run after the built-ins",
+			config: transformConfig{
+				Enabled: []string{transform_email},
+				Replace: []transformRule{{Pattern: `<email>`, Replacement: "nobody@example.com"}},
+			},
+			content: "alice@corp.dev",
+			want:    "nobody@example.com",
+		},
+		{
+			name:    "binary content is left alone",
+			config:  transformConfig{Enabled: []string{transform_line_endings}},
+			content: "a\r\n\x00b",
+			want:    "a\r\n\x00b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer, err := newTransformer(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := transformer.apply(tt.content); got != tt.want {
+				t.Errorf("apply(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformerInvalidConfig(t *testing.T) {
+	for _, config := range []transformConfig{
+		{Enabled: []string{"uppercase"}},
+		{Replace: []transformRule{{Pattern: `(`}}},
+	} {
+		if _, err := newTransformer(config); err == nil {
+			t.Errorf("newTransformer(%+v) accepted an invalid config", config)
+		}
+	}
+}
+
+func TestVisibleWhitespace(t *testing.T) {
+	got := visibleWhitespace("a \t\r\nb")
+	if want := "a \t␍\nb"; got != want {
+		t.Errorf("visibleWhitespace = %q, want %q", got, want)
+	}
+	got = visibleWhitespace("a  \nb")
+	if want := "a··\nb"; got != want {
+		t.Errorf("visibleWhitespace = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v74/github"
+)
+
+var err_truncated = errors.New("only part of it could be loaded")
+
+// the api stops listing files of a gist past this amount and flags the gist as truncated
+const gistFileCap = 300
+
+// files larger than this are not served through the raw url either, only through git
+const rawUrlLimit = 10 * 1024 * 1024
+
+// gistDetail holds the truncation flags go-github does not decode
+type gistDetail struct {
+	Truncated bool `json:"truncated"`
+	Files     map[string]struct {
+		Size      int  `json:"size"`
+		Truncated bool `json:"truncated"`
+	} `json:"files"`
+}
+
+func getGistDetail(ctx context.Context, client *github.Client, gistId string) (*gistDetail, error) {
+	req, err := client.NewRequest(http.MethodGet, "gists/"+gistId, nil)
+	if err != nil {
+		return nil, err
+	}
+	var detail gistDetail
+	if _, err := client.Do(ctx, req, &detail); err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+// apiContentTruncated tells whether the content the api returned for the file is only the first megabyte of it
+func apiContentTruncated(f github.GistFile) bool {
+	return f.GetSize() > len(f.GetContent())
+}
+
+// fullContent loads the content of the files for operations that re-create them somewhere else, edited
+// files bring their pending version. truncated files fail, a copy would hold only the part that was loaded
+func fullContent(files []file) ([]file, error) {
+	pending, err := withPendingContent(files)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range pending {
+		if f.truncated {
+			return nil, fmt.Errorf("%q: %w", f.title, err_truncated)
+		}
+		if f.dirty || f.draft {
+			continue
+		}
+		content, truncated, err := f.getContent()
+		if err != nil {
+			return nil, err
+		}
+		if truncated {
+			return nil, fmt.Errorf("%q: %w", f.title, err_truncated)
+		}
+		pending[i].content = content
+	}
+	return pending, nil
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/go-github/v74/github"
+	"github.com/ostafen/clover/v2/query"
+)
+
+// amount of gists uploaded at the same time by sync --push
+const uploadWorkers = 4
+
+// withPendingContent swaps the content of files with local changes for their pending version
+func withPendingContent(files []file) ([]file, error) {
+	pending := make([]file, len(files))
+	for i, f := range files {
+		if f.dirty {
+			buffer, err := loadBuffer(f.id)
+			if err != nil {
+				return nil, err
+			}
+			f.content = buffer
+		}
+		pending[i] = f
+	}
+	return pending, nil
+}
+
+// filesPayload holds the content of every file, binary files are left out since json would mangle them
+func filesPayload(files []file) map[github.GistFilename]github.GistFile {
+	payload := map[github.GistFilename]github.GistFile{}
+	for _, f := range files {
+		if isBinary([]byte(f.content)) {
+			continue
+		}
+		payload[github.GistFilename(f.title)] = github.GistFile{
+			Filename: github.Ptr(f.title),
+			Content:  github.Ptr(f.content),
+		}
+	}
+	return payload
+}
+
+// uploadedFiles are the files an upload sends, drafts send all of them and published gists only the
+// changed ones. truncated files are left out, github would keep only the part that could be loaded
+func uploadedFiles(wasDraft bool, files []file) []file {
+	var sent []file
+	for _, f := range files {
+		if f.truncated || (!wasDraft && !f.dirty) {
+			continue
+		}
+		sent = append(sent, f)
+	}
+	return sent
+}
+
+// skippedFiles counts the changed files an upload has to leave out because they are truncated
+func skippedFiles(files []file) int {
+	skipped := 0
+	for _, f := range files {
+		if f.truncated && f.dirty {
+			skipped++
+		}
+	}
+	return skipped
+}
+
+func gistPayload(name string, visibility gistVisibility, files []file) github.Gist {
+	return github.Gist{
+		Public:      github.Ptr(visibility == gist_public),
+		Description: &name,
+		Files:       filesPayload(files),
+	}
+}
+
+// sendGist creates the gist on github when it is still a draft, or pushes the files to the published one.
+// files are the ones the payload holds, the git backend writes nothing else into the mirror
+func sendGist(ctx context.Context, client *github.Client, gistId string, wasDraft bool, payload *github.Gist, files []file) (*github.Gist, error) {
+	switch {
+	case wasDraft:
+		g, _, err := client.Gists.Create(ctx, payload)
+		return g, err
+	case cfg.GitBackend:
+		change := gitChange{write: map[string][]byte{}, message: "Update files"}
+		for _, f := range files {
+			change.write[f.title] = []byte(f.content)
+		}
+		return pushGistChange(ctx, client, gistId, change)
+	default:
+		g, _, err := client.Gists.Edit(ctx, gistId, payload)
+		return g, err
+	}
+}
+
+// storeUploaded writes what github answered back into the collections and returns the files as they are now,
+// sent are the files the upload held
+func storeUploaded(previousId string, wasDraft bool, files, sent []file, response *github.Gist) ([]file, error) {
+	if wasDraft {
+		err := storage.db.Delete(query.NewQuery(string(collectionDraftedGists)).Where(query.Field("id").Eq(previousId)))
+		if err != nil {
+			return nil, fmt.Errorf("could not delete draft gist: %w", err)
+		}
+	}
+
+	// files that were not sent keep their document, it still matches what they hold
+	wasSent := map[string]bool{}
+	for _, f := range sent {
+		wasSent[f.id] = true
+	}
+
+	updatedAt := response.GetUpdatedAt().In(time.Local).String()
+	files = append([]file{}, files...)
+	for _, respFile := range response.GetFiles() {
+		for i, dbFile := range files {
+			if dbFile.title != respFile.GetFilename() || !wasSent[dbFile.id] {
+				continue
+			}
+			// the response only holds the first megabyte of large files and mangles binary ones,
+			// keep what we have locally instead
+			content := respFile.GetContent()
+			if apiContentTruncated(respFile) || isBinary([]byte(dbFile.content)) {
+				content = dbFile.content
+			}
+			updates := map[string]any{
+				"id":        dbFile.id,
+				"gistId":    response.GetID(),
+				"content":   content,
+				"updatedAt": updatedAt,
+				"draft":     false,
+				"rawUrl":    respFile.GetRawURL(),
+				"dirty":     false,
+				"buffer":    "",
+			}
+
+			q := query.NewQuery(string(collectionGistContent)).Where(query.Field("id").Eq(dbFile.id))
+			if err := storage.db.Update(q, updates); err != nil {
+				return nil, fmt.Errorf("could not update gist file %q in the collection: %w", respFile.GetFilename(), err)
+			}
+
+			files[i].gistId = response.GetID()
+			files[i].content = content
+			files[i].updatedAt = updatedAt
+			files[i].draft = false
+			files[i].rawUrl = respFile.GetRawURL()
+			files[i].dirty = false
+			break
+		}
+	}
+	return files, nil
+}
+
+type uploadProgress struct {
+	done   int
+	total  int
+	failed []string
+}
+
+// uploadAll uploads every drafted gist and every published gist with local changes at once
+func (m *model) uploadAll() []tea.Cmd {
+	if m.mainScreen.uploads.total > 0 {
+		return []tea.Cmd{showInfo("still uploading", info_default)}
+	}
+	cmds := m.mainScreen.stashEditor()
+
+	var pending []*gist
+	for g, items := range m.mainScreen.gists {
+		// github refuses gists without files
+		if len(items) == 0 {
+			continue
+		}
+		if g.status == gist_status_drafted {
+			pending = append(pending, g)
+			continue
+		}
+		for _, item := range items {
+			if f, ok := item.(file); ok && f.dirty {
+				pending = append(pending, g)
+				break
+			}
+		}
+	}
+
+	if len(pending) == 0 {
+		return append(cmds, showInfo("nothing to upload", info_default))
+	}
+
+	return append(cmds, m.transformUpload(uploadCheck{action: scan_upload_all, gists: pending})...)
+}
+
+// startUploads uploads the gists as one batch
+func (m *model) startUploads(pending []*gist) []tea.Cmd {
+	var cmds []tea.Cmd
+	m.mainScreen.uploads = uploadProgress{}
+	for _, g := range pending {
+		upload, started := m.uploadGist(g, true)
+		if started {
+			m.mainScreen.uploads.total++
+		}
+		cmds = append(cmds, upload...)
+	}
+	return cmds
+}
+
+// uploadProgressed counts a finished gist of the batch, and sums the batch up once every gist is done
+func (m *mainModel) uploadProgressed(name string, err error) []tea.Cmd {
+	m.uploads.done++
+	if err != nil {
+		log.Errorf("could not upload gist %q\n%v", name, err)
+		m.uploads.failed = append(m.uploads.failed, name)
+	}
+	if m.uploads.done < m.uploads.total {
+		return nil
+	}
+
+	progress := m.uploads
+	m.uploads = uploadProgress{}
+	if len(progress.failed) > 0 {
+		return []tea.Cmd{showInfo(
+			fmt.Sprintf("uploaded %d of %d gist(s), failed: %s", progress.total-len(progress.failed), progress.total, strings.Join(progress.failed, ", ")),
+			info_error,
+		)}
+	}
+	return []tea.Cmd{showInfo(fmt.Sprintf("uploaded %d gist(s)", progress.total), info_default)}
+}
+
+// pendingUpload is a gist with local changes, as found in the collections
+type pendingUpload struct {
+	id         string
+	name       string
+	visibility gistVisibility
+	wasDraft   bool
+	files      []file
+	// the files already hold their pending content, sync --push resolves it before the secret scan
+	resolved bool
+}
+
+// pendingUploads finds every drafted gist and every published file with local changes
+func pendingUploads() ([]pendingUpload, error) {
+	var uploads []pendingUpload
+
+	draftedDocs, err := storage.db.FindAll(query.NewQuery(string(collectionDraftedGists)))
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range draftedDocs {
+		upload := pendingUpload{
+			id:         doc.Get("id").(string),
+			name:       doc.Get("description").(string),
+			visibility: gistVisibility(doc.Get("visibility").(int64)),
+			wasDraft:   true,
+		}
+		fileDocs, err := storage.db.FindAll(
+			query.NewQuery(string(collectionGistContent)).Where(query.Field("gistId").Eq(upload.id).And(query.Field("draft").Eq(true))),
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, fileDoc := range fileDocs {
+			f := file{
+				id:     fileDoc.Get("id").(string),
+				gistId: upload.id,
+				title:  fileDoc.Get("title").(string),
+				draft:  true,
+			}
+			f.content, _ = fileDoc.Get("content").(string)
+			f.dirty, _ = fileDoc.Get("dirty").(bool)
+			f.truncated, _ = fileDoc.Get("truncated").(bool)
+			upload.files = append(upload.files, f)
+		}
+		if len(upload.files) > 0 {
+			uploads = append(uploads, upload)
+		}
+	}
+
+	dirtyDocs, err := storage.db.FindAll(
+		query.NewQuery(string(collectionGistContent)).Where(query.Field("dirty").Eq(true).And(query.Field("draft").Eq(false))),
+	)
+	if err != nil {
+		return nil, err
+	}
+	published := map[string]int{}
+	for _, doc := range dirtyDocs {
+		f := file{
+			id:     doc.Get("id").(string),
+			gistId: doc.Get("gistId").(string),
+			title:  doc.Get("title").(string),
+			dirty:  true,
+		}
+		f.truncated, _ = doc.Get("truncated").(bool)
+		if f.truncated {
+			log.Warnf("leaving out %q, it was only partially loaded", f.title)
+			continue
+		}
+		idx, ok := published[f.gistId]
+		if !ok {
+			desc, _ := doc.Get("desc").(string)
+			uploads = append(uploads, pendingUpload{id: f.gistId, name: desc})
+			idx = len(uploads) - 1
+			published[f.gistId] = idx
+		}
+		uploads[idx].files = append(uploads[idx].files, f)
+	}
+	return uploads, nil
+}
+
+type uploadResult struct {
+	upload pendingUpload
+	// amount of files the upload sent
+	sent int
+	err  error
+}
+
+// pushPending uploads the gists concurrently, a failing gist doesn't stop the others
+func pushPending(ctx context.Context, client *github.Client, uploads []pendingUpload) []uploadResult {
+	results := make([]uploadResult, len(uploads))
+	sem := make(chan struct{}, uploadWorkers)
+
+	var wg sync.WaitGroup
+	for i, upload := range uploads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			sent, err := pushOne(ctx, client, upload)
+			results[i] = uploadResult{upload: upload, sent: sent, err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// pushOne uploads a single gist and returns the amount of files it sent
+func pushOne(ctx context.Context, client *github.Client, upload pendingUpload) (int, error) {
+	files := upload.files
+	if !upload.resolved {
+		var err error
+		if files, err = withPendingContent(upload.files); err != nil {
+			return 0, err
+		}
+	}
+
+	// published gists only get the changed files, the description and visibility stay as they are on github
+	sent := uploadedFiles(upload.wasDraft, files)
+	payload := github.Gist{Files: filesPayload(sent)}
+	if upload.wasDraft {
+		payload = gistPayload(upload.name, upload.visibility, sent)
+	}
+
+	response, err := sendGist(ctx, client, upload.id, upload.wasDraft, &payload, sent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := storeUploaded(upload.id, upload.wasDraft, files, sent, response); err != nil {
+		return 0, err
+	}
+	return len(sent), nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/google/go-github/v74/github"
+	"github.com/ostafen/clover/v2/document"
+	"github.com/ostafen/clover/v2/query"
+)
+
+func TestUploadedFiles(t *testing.T) {
+	files := []file{
+		{id: "clean", title: "clean.go"},
+		{id: "dirty", title: "dirty.go", dirty: true},
+		{id: "truncated", title: "big.log", truncated: true},
+		{id: "truncated-dirty", title: "big.csv", truncated: true, dirty: true},
+	}
+	tests := []struct {
+		name     string
+		wasDraft bool
+		want     []string
+	}{
+		{name: "draft", wasDraft: true, want: []string{"clean", "dirty"}},
+		{name: "published", want: []string{"dirty"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range uploadedFiles(tt.wasDraft, files) {
+				got = append(got, f.id)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("uploadedFiles = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if skipped := skippedFiles(files); skipped != 1 {
+		t.Errorf("skippedFiles = %d, want 1", skipped)
+	}
+}
+
+func TestPushPending(t *testing.T) {
+	for _, resolved := range []bool{false, true} {
+		t.Run(fmt.Sprintf("resolved=%v", resolved), func(t *testing.T) {
+			gistId := fmt.Sprintf("push%v", resolved)
+			for _, fields := range []map[string]any{
+				{"id": gistId + "-clean", "title": "clean.txt", "content": "clean"},
+				{"id": gistId + "-dirty", "title": "notes.md", "content": "old", "dirty": true, "buffer": "new"},
+			} {
+				doc := document.NewDocument()
+				doc.SetAll(fields)
+				doc.SetAll(map[string]any{"gistId": gistId, "desc": "notes", "draft": false})
+				if err := storage.db.Insert(string(collectionGistContent), doc); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Cleanup(func() {
+				storage.db.Delete(query.NewQuery(string(collectionGistContent)).Where(query.Field("gistId").Eq(gistId)))
+			})
+
+			var edit github.Gist
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPatch || r.URL.Path != "/gists/"+gistId {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					http.NotFound(w, r)
+					return
+				}
+				if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+					t.Error(err)
+				}
+				fmt.Fprintf(w, `{"id": %q, "updated_at": "2025-03-14T15:09:26Z", "files": {"notes.md": {"filename": "notes.md", "raw_url": "https://gist.example.com/raw/2/notes.md", "content": "new", "size": 3}}}`, gistId)
+			}))
+			defer server.Close()
+			client := github.NewClient(nil)
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			uploads, err := pendingUploads()
+			if err != nil {
+				t.Fatal(err)
+			}
+			uploads = slices.DeleteFunc(uploads, func(u pendingUpload) bool { return u.id != gistId })
+			if len(uploads) != 1 {
+				t.Fatalf("pending uploads = %+v, want the one gist", uploads)
+			}
+			// sync --push resolves the pending content before the secret scan
+			if resolved {
+				if uploads[0].files, err = withPendingContent(uploads[0].files); err != nil {
+					t.Fatal(err)
+				}
+				uploads[0].resolved = true
+			}
+
+			results := pushPending(context.Background(), client, uploads)
+			if results[0].err != nil {
+				t.Fatal(results[0].err)
+			}
+			if results[0].sent != 1 {
+				t.Errorf("sent = %d, want 1", results[0].sent)
+			}
+			if sentFile := edit.Files["notes.md"]; len(edit.Files) != 1 || sentFile.GetContent() != "new" {
+				t.Errorf("edit payload = %+v, want only notes.md with the new content", edit.Files)
+			}
+
+			doc, err := storage.db.FindFirst(query.NewQuery(string(collectionGistContent)).Where(query.Field("id").Eq(gistId + "-dirty")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dirty, _ := doc.Get("dirty").(bool); dirty {
+				t.Error("the uploaded file is still dirty")
+			}
+			if content, _ := doc.Get("content").(string); content != "new" {
+				t.Errorf("stored content = %q, want %q", content, "new")
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/google/go-github/v74/github"
+	"github.com/urfave/cli/v3"
+)
+
+var (
+	err_no_links   = errors.New("drafts have no urls until they are uploaded")
+	err_no_raw_url = errors.New("select an uploaded file to copy its raw url")
+)
+
+// what can be copied about a gist
+const (
+	link_html  = "url"
+	link_raw   = "raw"
+	link_clone = "clone"
+	link_embed = "embed"
+)
+
+var linkLabels = map[string]string{
+	link_html:  "gist url",
+	link_raw:   "raw url",
+	link_clone: "clone url",
+	link_embed: "embed snippet",
+}
+
+// gistLinks are the urls of an uploaded gist, raw belongs to a single file of it
+type gistLinks struct {
+	html  string
+	raw   string
+	clone string
+}
+
+func (l gistLinks) get(kind string) (string, error) {
+	switch kind {
+	case link_html:
+		return l.html, nil
+	case link_raw:
+		if l.raw == "" {
+			return "", err_no_raw_url
+		}
+		return l.raw, nil
+	case link_clone:
+		return l.clone, nil
+	case link_embed:
+		return fmt.Sprintf(`<script src="%s.js"></script>`, l.html), nil
+	}
+	return "", fmt.Errorf("unknown link %q, expected one of %s, %s, %s or %s", kind, link_html, link_raw, link_clone, link_embed)
+}
+
+// openBrowser opens the url with whatever the system uses for links
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// the browser outlives the opener, it only has to be reaped
+	go cmd.Wait()
+	return nil
+}
+
+// selectedLinks are the urls of the selected gist, with the raw url of the selected file
+func (m *mainModel) selectedLinks() (gistLinks, error) {
+	g := m.selectedGist()
+	if g == nil || g.status == gist_status_drafted {
+		return gistLinks{}, err_no_links
+	}
+	links := gistLinks{html: g.htmlURL(), clone: g.htmlURL() + ".git"}
+	if f, ok := m.fileList.SelectedItem().(file); ok && f.gistId == g.id && !f.draft {
+		links.raw = f.rawUrl
+	}
+	return links, nil
+}
+
+func (m *mainModel) openGist() tea.Cmd {
+	links, err := m.selectedLinks()
+	if err != nil {
+		return showInfo(err.Error(), info_default)
+	}
+	if err := openBrowser(links.html); err != nil {
+		log.Errorf("could not open %s\n%v", links.html, err)
+		return showInfo("could not open the browser", info_error)
+	}
+	return showInfo("opened in the browser", info_default)
+}
+
+func (m *mainModel) copyLink(kind string) tea.Cmd {
+	links, err := m.selectedLinks()
+	if err != nil {
+		return showInfo(err.Error(), info_default)
+	}
+	text, err := links.get(kind)
+	if err != nil {
+		return showInfo(err.Error(), info_default)
+	}
+	return copyCmd(text, fmt.Sprintf("%s copied to clipboard", linkLabels[kind]))
+}
+
+// linkOptions are the links the copy dialog offers, the raw url only with a file selected
+func (m *mainModel) linkOptions() []huh.Option[string] {
+	links, _ := m.selectedLinks()
+	options := []huh.Option[string]{huh.NewOption("Gist URL", link_html)}
+	if links.raw != "" {
+		options = append(options, huh.NewOption("Raw URL of the file", link_raw))
+	}
+	return append(options,
+		huh.NewOption("Clone URL", link_clone),
+		huh.NewOption("Embed snippet", link_embed),
+	)
+}
+
+// openCommand opens a gist in the browser, or copies one of its links with --copy
+func openCommand(ctx context.Context, c *cli.Command) error {
+	if !cfg.hasAccessToken() {
+		return err_unauthorized
+	}
+	gistId := c.Args().Get(0)
+	if gistId == "" {
+		return errors.New("missing the id of the gist to open")
+	}
+
+	client := newClient(cfg.AccessToken)
+	g, _, err := client.Gists.Get(ctx, gistId)
+	if err != nil {
+		var errRes *github.ErrorResponse
+		if errors.As(err, &errRes) {
+			return errors.New(errRes.Message)
+		}
+		return err
+	}
+
+	links := gistLinks{html: g.GetHTMLURL(), clone: g.GetGitPullURL()}
+	if filename := c.Args().Get(1); filename != "" {
+		f, ok := g.Files[github.GistFilename(filename)]
+		if !ok {
+			return fmt.Errorf("gist %q has no file %q", gistId, filename)
+		}
+		links.raw = f.GetRawURL()
+	} else if len(g.Files) == 1 {
+		for _, f := range g.Files {
+			links.raw = f.GetRawURL()
+		}
+	}
+
+	kind := strings.ToLower(c.String("copy"))
+	if kind == "" {
+		fmt.Println(links.html)
+		return openBrowser(links.html)
+	}
+	text, err := links.get(kind)
+	if err != nil {
+		return err
+	}
+	if err := copyText(text); err != nil {
+		return err
+	}
+	fmt.Printf("%s copied to clipboard\n", linkLabels[kind])
+	return nil
+}
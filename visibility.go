@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/go-github/v74/github"
+	"github.com/ostafen/clover/v2/query"
+)
+
+type visibilityChangedMsg struct {
+	gist *gist
+	// id of the original gist, published gists are re-created under a new one
+	previousId string
+	visibility gistVisibility
+	files      []file
+	// the re-created gist, nil when it is a draft
+	response *github.Gist
+	err      error
+	// the gist got re-created but the original could not be deleted
+	deleteErr error
+}
+
+func (v gistVisibility) String() string {
+	if v == gist_public {
+		return "public"
+	}
+	return "secret"
+}
+
+// visibilityTargets returns the marked gists, or the selected gist when none are marked
+func (m *mainModel) visibilityTargets() []*gist {
+	if len(m.marks.gists) > 0 {
+		return m.marks.markedGists()
+	}
+	if g := m.selectedGist(); g != nil {
+		return []*gist{g}
+	}
+	return nil
+}
+
+// changeVisibility updates drafts in place, github can't change the visibility of a published gist
+// so it gets re-created with the other visibility instead
+func (m *model) changeVisibility(g *gist, visibility gistVisibility, deleteOriginal bool) []tea.Cmd {
+	if g.visiblity == visibility {
+		return nil
+	}
+
+	if g.status == gist_status_drafted {
+		q := query.NewQuery(string(collectionDraftedGists)).Where(query.Field("id").Eq(g.id))
+		return []tea.Cmd{func() tea.Msg {
+			err := storage.db.Update(q, map[string]any{"visibility": visibility})
+			return visibilityChangedMsg{gist: g, previousId: g.id, visibility: visibility, err: err}
+		}}
+	}
+
+	// the re-created gist would lack every file the api did not list, and the original gets deleted
+	if g.truncated {
+		return []tea.Cmd{showInfo(fmt.Sprintf("%s has more files than the api lists, it can't be re-created", g.name), info_error)}
+	}
+
+	files := []file{}
+	for _, item := range m.mainScreen.gists[g] {
+		f, _ := item.(file)
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return []tea.Cmd{showInfo(fmt.Sprintf("%s has no files to re-create it with", g.name), info_error)}
+	}
+	return m.recreateGist(g, visibility, deleteOriginal, files, false)
+}
+
+// recreateGist creates the gist again with the other visibility. the content gets loaded and scanned
+// for secrets first, unless the files were reviewed already and hold what has to be sent
+func (m *model) recreateGist(g *gist, visibility gistVisibility, deleteOriginal bool, files []file, reviewed bool) []tea.Cmd {
+	ctx, tick, ok := m.mainScreen.syncs.begin(g.id)
+	if !ok {
+		return []tea.Cmd{showInfo("gist is still syncing", info_default)}
+	}
+
+	client := m.client
+	name := g.name
+	msg := visibilityChangedMsg{gist: g, previousId: g.id, visibility: visibility}
+	return []tea.Cmd{tick, func() tea.Msg {
+		pending := files
+		if !reviewed {
+			var err error
+			if pending, err = fullContent(files); err != nil {
+				msg.err = err
+				return msg
+			}
+			if found, err := scanTransfer(g, visibility, pending); err != nil || len(found) > 0 {
+				msg.err = err
+				if len(found) > 0 {
+					return secretsFoundMsg{uploadCheck: uploadCheck{
+						action: scan_visibility, gist: g, files: pending, visibility: visibility, deleteOriginal: deleteOriginal,
+					}, found: found}
+				}
+				return msg
+			}
+		}
+		msg.files = pending
+
+		payload := gistPayload(name, visibility, pending)
+		if len(payload.Files) == 0 {
+			msg.err = errors.New("github can't create a gist out of binary files only")
+			return msg
+		}
+		if msg.response, _, msg.err = client.Gists.Create(ctx, &payload); msg.err != nil {
+			return msg
+		}
+
+		// json would mangle binary content, those can only go through git
+		binaries := gitChange{write: map[string][]byte{}, message: "Add binary files"}
+		for _, f := range pending {
+			if isBinary([]byte(f.content)) {
+				binaries.write[f.title] = []byte(f.content)
+			}
+		}
+		if len(binaries.write) > 0 {
+			if msg.response, msg.err = pushGistChange(ctx, client, msg.response.GetID(), binaries); msg.err != nil {
+				return msg
+			}
+		}
+
+		if deleteOriginal {
+			_, msg.deleteErr = client.Gists.Delete(ctx, msg.previousId)
+		}
+		return msg
+	}}
+}
+
+func (m *model) visibilityChanged(msg visibilityChangedMsg) []tea.Cmd {
+	var cmds []tea.Cmd
+	g := msg.gist
+
+	if msg.response != nil || msg.err != nil {
+		m.mainScreen.syncs.done(msg.previousId)
+	}
+	if errors.Is(msg.err, err_truncated) {
+		return []tea.Cmd{showInfo(fmt.Sprintf("could not make %s %s, %v", g.name, msg.visibility, msg.err), info_error)}
+	}
+	if msg.err != nil {
+		return syncFailed(fmt.Sprintf("could not make %s %s", g.name, msg.visibility), msg.err)
+	}
+
+	g.visiblity = msg.visibility
+	if msg.response != nil {
+		// the stored files now belong to the re-created gist, all of them went into it
+		files, err := storeUploaded(msg.previousId, false, msg.files, msg.files, msg.response)
+		if err != nil {
+			log.Errorf("could not store re-created gist %q\n%v", g.name, err)
+			return []tea.Cmd{showInfo("could not store re-created gist", info_error)}
+		}
+		items := make([]list.Item, 0, len(files))
+		for _, f := range files {
+			items = append(items, f)
+			if m.mainScreen.editorFile == f.id {
+				m.mainScreen.editorSaved = f.content
+			}
+		}
+		m.mainScreen.gists[g] = items
+		g.id = msg.response.GetID()
+		g.htmlUrl = msg.response.GetHTMLURL()
+		g.updatedAt = msg.response.GetUpdatedAt().In(time.Local)
+		cmds = append(cmds, m.mainScreen.refreshFileList(g)...)
+	}
+	if m.mainScreen.gistFilter != gist_filter_all {
+		// the gist might not match the filter anymore
+		cmds = append(cmds, m.mainScreen.applyGistFilter()...)
+	} else if idx := m.mainScreen.gistIndex(g); idx >= 0 {
+		cmds = append(cmds, m.mainScreen.gistList.SetItem(idx, g))
+	}
+
+	if msg.deleteErr != nil {
+		return append(cmds, syncFailed(fmt.Sprintf("%s is now %s but the original could not be deleted", g.name, msg.visibility), msg.deleteErr)...)
+	}
+	info := fmt.Sprintf("%s is now %s", g.name, msg.visibility)
+	if msg.response != nil {
+		info += ", its urls changed"
+	}
+	return append(cmds, showInfo(info, info_default))
+}